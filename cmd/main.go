@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/Darari17/social-media/internal/configs"
+	"github.com/Darari17/social-media/internal/repos"
 	"github.com/Darari17/social-media/internal/routers"
 	"github.com/joho/godotenv"
 )
@@ -38,6 +44,55 @@ func main() {
 		return
 	}
 
+	// init media storage
+	store, err := configs.InitStorage()
+	if err != nil {
+		log.Println("Failed to init storage.\nCause:", err.Error())
+		return
+	}
+
+	policies, err := configs.InitUploadPolicies()
+	if err != nil {
+		log.Println("Failed to read upload policies.\nCause:", err.Error())
+		return
+	}
+
+	postPolicy, err := configs.InitPostPolicy()
+	if err != nil {
+		log.Println("Failed to read post policy.\nCause:", err.Error())
+		return
+	}
+
+	gcInterval, gcGrace, err := configs.InitMediaGC()
+	if err != nil {
+		log.Println("Failed to read media gc config.\nCause:", err.Error())
+		return
+	}
+	mediaRepo := repos.NewMediaRepo(db, store)
+
+	// `server gc [--dry-run] [--grace 24h]` collects unreferenced media once
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		gc := flag.NewFlagSet("gc", flag.ExitOnError)
+		dryRun := gc.Bool("dry-run", false, "only list the files that would be deleted")
+		grace := gc.Duration("grace", gcGrace, "keep unreferenced files used more recently than this")
+		gc.Parse(os.Args[2:])
+
+		keys, err := mediaRepo.CollectGarbage(context.Background(), *grace, *dryRun)
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+		if err != nil {
+			log.Println("Media garbage collection failed.\nCause:", err.Error())
+			return
+		}
+		if *dryRun {
+			log.Printf("%d files would be deleted\n", len(keys))
+		} else {
+			log.Printf("%d files deleted\n", len(keys))
+		}
+		return
+	}
+
 	// init redis
 	rdb, err := configs.InitRedis()
 	if err != nil {
@@ -47,7 +102,16 @@ func main() {
 	log.Println("Redis Connected.")
 	defer rdb.Close()
 
+	// background jobs; each takes an advisory lock so one instance at a time
+	// runs it
+	if gcInterval > 0 {
+		mediaRepo.StartGC(gcInterval, gcGrace)
+	}
+	// deleted posts past their retention are purged hourly
+	repos.NewPostRepo(db, rdb, store).StartPurge(time.Hour, postPolicy.TrashRetention)
+	routers.StartPostScheduler(db, rdb, store, postPolicy)
+
 	// router
-	router := routers.InitRouter(db, rdb)
+	router := routers.InitRouter(db, rdb, store, policies, postPolicy)
 	router.Run(":8080")
 }
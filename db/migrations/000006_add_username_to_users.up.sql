@@ -0,0 +1,6 @@
+ALTER TABLE
+  public.users
+ADD
+  COLUMN username character varying(30) NULL;
+
+CREATE UNIQUE INDEX users_username_key ON public.users (lower(username));
@@ -0,0 +1,20 @@
+CREATE TABLE
+  public.mentions (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    post_id integer NOT NULL,
+    comment_id integer NULL,
+    author_id integer NOT NULL,
+    mentioned_user_id integer NOT NULL,
+    start_offset integer NOT NULL,
+    length integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.mentions
+ADD
+  CONSTRAINT mentions_pkey PRIMARY KEY (id);
+
+CREATE INDEX mentions_post_id_idx ON public.mentions (post_id);
+
+CREATE INDEX mentions_mentioned_user_id_idx ON public.mentions (mentioned_user_id);
@@ -0,0 +1,18 @@
+CREATE TABLE
+  public.notifications (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    actor_id integer NOT NULL,
+    type character varying(30) NOT NULL,
+    post_id integer NULL,
+    comment_id integer NULL,
+    read_at timestamp without time zone NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.notifications
+ADD
+  CONSTRAINT notifications_pkey PRIMARY KEY (id);
+
+CREATE INDEX notifications_user_id_idx ON public.notifications (user_id, created_at DESC);
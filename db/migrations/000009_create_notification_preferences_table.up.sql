@@ -0,0 +1,12 @@
+CREATE TABLE
+  public.notification_preferences (
+    user_id integer NOT NULL,
+    type character varying(30) NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    updated_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.notification_preferences
+ADD
+  CONSTRAINT notification_preferences_pkey PRIMARY KEY (user_id, type);
@@ -0,0 +1,14 @@
+CREATE TABLE
+  public.conversations (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    is_group boolean NOT NULL DEFAULT false,
+    title character varying(100) NULL,
+    created_by integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.conversations
+ADD
+  CONSTRAINT conversations_pkey PRIMARY KEY (id);
@@ -0,0 +1,15 @@
+CREATE TABLE
+  public.conversation_members (
+    conversation_id integer NOT NULL,
+    user_id integer NOT NULL,
+    last_read_message_id integer NULL,
+    last_read_at timestamp without time zone NULL,
+    joined_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.conversation_members
+ADD
+  CONSTRAINT conversation_members_pkey PRIMARY KEY (conversation_id, user_id);
+
+CREATE INDEX conversation_members_user_id_idx ON public.conversation_members (user_id);
@@ -0,0 +1,16 @@
+CREATE TABLE
+  public.messages (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    conversation_id integer NOT NULL,
+    sender_id integer NOT NULL,
+    content text NULL,
+    image text NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.messages
+ADD
+  CONSTRAINT messages_pkey PRIMARY KEY (id);
+
+CREATE INDEX messages_conversation_id_idx ON public.messages (conversation_id, id DESC);
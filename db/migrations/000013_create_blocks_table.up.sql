@@ -0,0 +1,13 @@
+CREATE TABLE
+  public.blocks (
+    blocker_id integer NOT NULL,
+    blocked_id integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.blocks
+ADD
+  CONSTRAINT blocks_pkey PRIMARY KEY (blocker_id, blocked_id);
+
+CREATE INDEX blocks_blocked_id_idx ON public.blocks (blocked_id);
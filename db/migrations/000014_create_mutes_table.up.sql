@@ -0,0 +1,11 @@
+CREATE TABLE
+  public.mutes (
+    muter_id integer NOT NULL,
+    muted_id integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.mutes
+ADD
+  CONSTRAINT mutes_pkey PRIMARY KEY (muter_id, muted_id);
@@ -0,0 +1,4 @@
+ALTER TABLE
+  public.users
+ADD
+  COLUMN is_private boolean NOT NULL DEFAULT false;
@@ -0,0 +1,14 @@
+CREATE TABLE
+  public.follow_requests (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    requester_id integer NOT NULL,
+    target_id integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.follow_requests
+ADD
+  CONSTRAINT follow_requests_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX follow_requests_requester_target_key ON public.follow_requests (requester_id, target_id);
@@ -0,0 +1,7 @@
+DELETE FROM follows a
+USING follows b
+WHERE a.follower_id = b.follower_id
+  AND a.following_id = b.following_id
+  AND a.id > b.id;
+
+CREATE UNIQUE INDEX follows_follower_following_key ON public.follows (follower_id, following_id);
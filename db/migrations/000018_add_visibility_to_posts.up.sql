@@ -0,0 +1,4 @@
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN visibility character varying(20) NOT NULL DEFAULT 'public';
@@ -0,0 +1,11 @@
+CREATE TABLE
+  public.close_friends (
+    user_id integer NOT NULL,
+    friend_id integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.close_friends
+ADD
+  CONSTRAINT close_friends_pkey PRIMARY KEY (user_id, friend_id);
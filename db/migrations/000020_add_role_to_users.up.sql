@@ -0,0 +1,4 @@
+ALTER TABLE
+  public.users
+ADD
+  COLUMN role character varying(20) NOT NULL DEFAULT 'user';
@@ -0,0 +1,9 @@
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN hidden_at timestamp without time zone NULL;
+
+ALTER TABLE
+  public.comments
+ADD
+  COLUMN hidden_at timestamp without time zone NULL;
@@ -0,0 +1,22 @@
+CREATE TABLE
+  public.reports (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    reporter_id integer NOT NULL,
+    target_type character varying(20) NOT NULL,
+    target_id integer NOT NULL,
+    reason character varying(30) NOT NULL,
+    details text NULL,
+    status character varying(20) NOT NULL DEFAULT 'open',
+    resolved_by integer NULL,
+    resolved_at timestamp without time zone NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.reports
+ADD
+  CONSTRAINT reports_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX reports_reporter_target_key ON public.reports (reporter_id, target_type, target_id);
+
+CREATE INDEX reports_status_idx ON public.reports (status, created_at);
@@ -0,0 +1,16 @@
+CREATE TABLE
+  public.moderation_actions (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    moderator_id integer NOT NULL,
+    action character varying(20) NOT NULL,
+    target_type character varying(20) NOT NULL,
+    target_id integer NOT NULL,
+    report_id integer NULL,
+    reason text NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.moderation_actions
+ADD
+  CONSTRAINT moderation_actions_pkey PRIMARY KEY (id);
@@ -0,0 +1,8 @@
+ALTER TABLE
+  public.users
+ADD
+  COLUMN suspended_at timestamp without time zone NULL,
+ADD
+  COLUMN suspended_until timestamp without time zone NULL,
+ADD
+  COLUMN suspension_reason text NULL;
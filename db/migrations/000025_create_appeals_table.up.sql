@@ -0,0 +1,19 @@
+CREATE TABLE
+  public.appeals (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    message text NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'open',
+    reviewed_by integer NULL,
+    reviewed_at timestamp without time zone NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.appeals
+ADD
+  CONSTRAINT appeals_pkey PRIMARY KEY (id);
+
+CREATE INDEX appeals_status_idx ON public.appeals (status, created_at);
+
+CREATE UNIQUE INDEX appeals_user_open_key ON public.appeals (user_id) WHERE status = 'open';
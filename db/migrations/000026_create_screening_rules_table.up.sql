@@ -0,0 +1,16 @@
+CREATE TABLE
+  public.screening_rules (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    kind character varying(10) NOT NULL,
+    pattern text NOT NULL,
+    outcome character varying(10) NOT NULL,
+    created_by integer NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.screening_rules
+ADD
+  CONSTRAINT screening_rules_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX screening_rules_kind_pattern_key ON public.screening_rules (kind, pattern);
@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS content_warning;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS content_warning;
@@ -0,0 +1,9 @@
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN content_warning text NULL;
+
+ALTER TABLE
+  public.comments
+ADD
+  COLUMN content_warning text NULL;
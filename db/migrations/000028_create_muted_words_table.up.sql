@@ -0,0 +1,14 @@
+CREATE TABLE
+  public.muted_words (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    word character varying(100) NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.muted_words
+ADD
+  CONSTRAINT muted_words_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX muted_words_user_word_key ON public.muted_words (user_id, word);
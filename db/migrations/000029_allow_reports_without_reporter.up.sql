@@ -0,0 +1,4 @@
+ALTER TABLE
+  public.reports
+ALTER COLUMN
+  reporter_id DROP NOT NULL;
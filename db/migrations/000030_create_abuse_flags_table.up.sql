@@ -0,0 +1,17 @@
+CREATE TABLE
+  public.abuse_flags (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    signal character varying(30) NOT NULL,
+    count integer NOT NULL,
+    window_seconds integer NOT NULL,
+    details text NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.abuse_flags
+ADD
+  CONSTRAINT abuse_flags_pkey PRIMARY KEY (id);
+
+CREATE INDEX abuse_flags_user_idx ON public.abuse_flags (user_id, created_at);
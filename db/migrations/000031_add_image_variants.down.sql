@@ -0,0 +1,2 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS content_image_variants;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_variants;
@@ -0,0 +1,9 @@
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN content_image_variants jsonb NULL;
+
+ALTER TABLE
+  public.users
+ADD
+  COLUMN avatar_variants jsonb NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_image text NULL;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_image_variants jsonb NULL;
+
+UPDATE posts p SET content_image = m.filename, content_image_variants = m.variants
+FROM post_media m
+WHERE m.post_id = p.id AND m.position = 0;
+
+DROP TABLE IF EXISTS post_media;
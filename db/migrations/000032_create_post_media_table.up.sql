@@ -0,0 +1,33 @@
+CREATE TABLE
+  public.post_media (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    post_id integer NOT NULL,
+    position integer NOT NULL,
+    filename text NOT NULL,
+    variants jsonb NULL,
+    alt_text text NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.post_media
+ADD
+  CONSTRAINT post_media_pkey PRIMARY KEY (id);
+
+CREATE INDEX post_media_post_id_idx ON public.post_media (post_id, position);
+
+INSERT INTO
+  public.post_media (post_id, position, filename, variants, created_at)
+SELECT
+  id, 0, content_image, content_image_variants, created_at
+FROM
+  public.posts
+WHERE
+  content_image IS NOT NULL AND content_image <> '';
+
+ALTER TABLE
+  public.posts
+DROP
+  COLUMN content_image,
+DROP
+  COLUMN content_image_variants;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS messages_image_idx;
+DROP INDEX IF EXISTS users_avatar_idx;
+DROP INDEX IF EXISTS post_media_filename_idx;
+DROP TABLE IF EXISTS media_files;
@@ -0,0 +1,69 @@
+CREATE TABLE
+  public.media_files (
+    key text NOT NULL,
+    upload text NOT NULL,
+    variant character varying(30) NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size_bytes bigint NOT NULL,
+    content_type character varying(100) NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.media_files
+ADD
+  CONSTRAINT media_files_pkey PRIMARY KEY (key);
+
+CREATE INDEX media_files_upload_idx ON public.media_files (upload);
+
+CREATE INDEX post_media_filename_idx ON public.post_media (filename);
+
+CREATE INDEX users_avatar_idx ON public.users (avatar);
+
+CREATE INDEX messages_image_idx ON public.messages (image);
+
+-- files stored before the registry, so they are collected once replaced; their
+-- sizes are unknown
+INSERT INTO
+  public.media_files (key, upload, variant, width, height, size_bytes, content_type)
+SELECT
+  v.value ->> 'filename', pm.filename, v.key, (v.value ->> 'width')::integer, (v.value ->> 'height')::integer, 0, ''
+FROM
+  public.post_media pm,
+  jsonb_each(pm.variants) v
+ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO
+  public.media_files (key, upload, variant, width, height, size_bytes, content_type)
+SELECT
+  v.value ->> 'filename', u.avatar, v.key, (v.value ->> 'width')::integer, (v.value ->> 'height')::integer, 0, ''
+FROM
+  public.users u,
+  jsonb_each(u.avatar_variants) v
+WHERE
+  u.avatar IS NOT NULL
+ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO
+  public.media_files (key, upload, variant, width, height, size_bytes, content_type)
+SELECT
+  filename, filename, 'full', 0, 0, 0, ''
+FROM
+  public.post_media
+UNION
+SELECT
+  avatar, avatar, 'full', 0, 0, 0, ''
+FROM
+  public.users
+WHERE
+  avatar IS NOT NULL AND avatar <> ''
+UNION
+SELECT
+  image, image, 'full', 0, 0, 0, ''
+FROM
+  public.messages
+WHERE
+  image IS NOT NULL AND image <> ''
+ON CONFLICT (key) DO NOTHING;
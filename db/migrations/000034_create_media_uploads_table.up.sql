@@ -0,0 +1,21 @@
+CREATE TABLE
+  public.media_uploads (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'uploading',
+    size_bytes bigint NOT NULL,
+    filename text NULL,
+    variants jsonb NULL,
+    post_id integer NULL,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.media_uploads
+ADD
+  CONSTRAINT media_uploads_pkey PRIMARY KEY (id);
+
+CREATE INDEX media_uploads_user_id_idx ON public.media_uploads (user_id, status);
+
+CREATE INDEX media_uploads_filename_idx ON public.media_uploads (filename);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS messages_sender_id_image_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS storage_quota;
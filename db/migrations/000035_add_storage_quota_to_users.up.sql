@@ -0,0 +1,7 @@
+-- bytes of media the user can store; NULL uses the configured default
+ALTER TABLE
+  public.users
+ADD
+  COLUMN storage_quota bigint NULL;
+
+CREATE INDEX messages_sender_id_image_idx ON public.messages (sender_id) WHERE image IS NOT NULL;
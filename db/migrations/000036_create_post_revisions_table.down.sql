@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_revision_media;
+DROP TABLE IF EXISTS post_revisions;
+ALTER TABLE posts DROP COLUMN IF EXISTS revision_count;
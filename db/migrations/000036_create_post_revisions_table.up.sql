@@ -0,0 +1,43 @@
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN revision_count integer NOT NULL DEFAULT 0;
+
+-- a version of a post replaced by an edit
+CREATE TABLE
+  public.post_revisions (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    post_id integer NOT NULL,
+    revision integer NOT NULL,
+    content_text text NULL,
+    content_warning text NULL,
+    created_at timestamp without time zone NOT NULL,
+    replaced_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.post_revisions
+ADD
+  CONSTRAINT post_revisions_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX post_revisions_post_id_revision_idx ON public.post_revisions (post_id, revision);
+
+-- the gallery of a replaced version
+CREATE TABLE
+  public.post_revision_media (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    revision_id integer NOT NULL,
+    position integer NOT NULL,
+    filename text NOT NULL,
+    variants jsonb NULL,
+    alt_text text NULL
+  );
+
+ALTER TABLE
+  public.post_revision_media
+ADD
+  CONSTRAINT post_revision_media_pkey PRIMARY KEY (id);
+
+CREATE INDEX post_revision_media_revision_id_idx ON public.post_revision_media (revision_id, position);
+
+CREATE INDEX post_revision_media_filename_idx ON public.post_revision_media (filename);
@@ -0,0 +1,9 @@
+-- drafts are only seen by their author, scheduled posts until publish_at
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN status text NOT NULL DEFAULT 'published',
+ADD
+  COLUMN publish_at timestamp without time zone NULL;
+
+CREATE INDEX posts_publish_at_idx ON public.posts (publish_at) WHERE status = 'scheduled';
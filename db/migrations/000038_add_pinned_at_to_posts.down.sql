@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS comments_user_id_idx;
+DROP INDEX IF EXISTS posts_user_id_pinned_at_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS pinned_at;
@@ -0,0 +1,9 @@
+-- pinned posts are listed first on their author's profile
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN pinned_at timestamp without time zone NULL;
+
+CREATE INDEX posts_user_id_pinned_at_idx ON public.posts (user_id, pinned_at) WHERE pinned_at IS NOT NULL;
+
+CREATE INDEX comments_user_id_idx ON public.comments (user_id, post_id);
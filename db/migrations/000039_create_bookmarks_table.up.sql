@@ -0,0 +1,36 @@
+-- named folders a user sorts their bookmarks into
+CREATE TABLE
+  public.bookmark_collections (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.bookmark_collections
+ADD
+  CONSTRAINT bookmark_collections_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX bookmark_collections_user_name_key ON public.bookmark_collections (user_id, lower(name));
+
+-- posts a user saved for later, seen by nobody else
+CREATE TABLE
+  public.bookmarks (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    user_id integer NOT NULL,
+    post_id integer NOT NULL,
+    collection_id integer NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.bookmarks
+ADD
+  CONSTRAINT bookmarks_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX bookmarks_user_post_key ON public.bookmarks (user_id, post_id);
+
+CREATE INDEX bookmarks_user_id_collection_id_idx ON public.bookmarks (user_id, collection_id, created_at);
+
+CREATE INDEX bookmarks_post_id_idx ON public.bookmarks (post_id);
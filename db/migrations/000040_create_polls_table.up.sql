@@ -0,0 +1,50 @@
+-- a poll attached to a post
+CREATE TABLE
+  public.polls (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    post_id integer NOT NULL,
+    multiple boolean NOT NULL DEFAULT false,
+    voters_count integer NOT NULL DEFAULT 0,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.polls
+ADD
+  CONSTRAINT polls_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX polls_post_id_key ON public.polls (post_id);
+
+CREATE TABLE
+  public.poll_options (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    poll_id integer NOT NULL,
+    position integer NOT NULL,
+    text character varying(100) NOT NULL,
+    votes_count integer NOT NULL DEFAULT 0
+  );
+
+ALTER TABLE
+  public.poll_options
+ADD
+  CONSTRAINT poll_options_pkey PRIMARY KEY (id);
+
+CREATE INDEX poll_options_poll_id_idx ON public.poll_options (poll_id, position);
+
+-- one row per voter, holding every option they chose
+CREATE TABLE
+  public.poll_votes (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    poll_id integer NOT NULL,
+    user_id integer NOT NULL,
+    option_ids integer[] NOT NULL,
+    created_at timestamp without time zone NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.poll_votes
+ADD
+  CONSTRAINT poll_votes_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX poll_votes_poll_user_key ON public.poll_votes (poll_id, user_id);
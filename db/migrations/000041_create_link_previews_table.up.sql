@@ -0,0 +1,25 @@
+-- the first link of a post, shown with its preview
+ALTER TABLE
+  public.posts
+ADD
+  COLUMN link_url text NULL;
+
+-- previews of web pages, shared by every post linking to the same URL
+CREATE TABLE
+  public.link_previews (
+    id integer NOT NULL GENERATED ALWAYS AS IDENTITY,
+    url text NOT NULL,
+    status text NOT NULL,
+    title text NULL,
+    description text NULL,
+    image_url text NULL,
+    site_name text NULL,
+    fetched_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+ALTER TABLE
+  public.link_previews
+ADD
+  CONSTRAINT link_previews_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX link_previews_url_key ON public.link_previews (url);
@@ -0,0 +1,49 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Darari17/social-media/internal/utils"
+)
+
+// InitPostPolicy reads from POST_EDIT_WINDOW how long after posting the
+// content and images of a post can be edited (unset or 0 keeps posts
+// editable), from POST_TRASH_RETENTION how long deleted posts can be
+// restored before they are purged, from POST_SCHEDULE_INTERVAL how often
+// scheduled posts are published and from POST_MAX_PINNED how many posts a
+// user can pin to their profile.
+func InitPostPolicy() (utils.PostPolicy, error) {
+	policy := utils.DefaultPostPolicy
+	if v := os.Getenv("POST_EDIT_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < 0 {
+			return policy, fmt.Errorf("invalid POST_EDIT_WINDOW %q", v)
+		}
+		policy.EditWindow = window
+	}
+	if v := os.Getenv("POST_TRASH_RETENTION"); v != "" {
+		retention, err := time.ParseDuration(v)
+		if err != nil || retention <= 0 {
+			return policy, fmt.Errorf("invalid POST_TRASH_RETENTION %q", v)
+		}
+		policy.TrashRetention = retention
+	}
+	if v := os.Getenv("POST_SCHEDULE_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return policy, fmt.Errorf("invalid POST_SCHEDULE_INTERVAL %q", v)
+		}
+		policy.ScheduleInterval = interval
+	}
+	if v := os.Getenv("POST_MAX_PINNED"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("invalid POST_MAX_PINNED %q", v)
+		}
+		policy.MaxPinned = n
+	}
+	return policy, nil
+}
@@ -0,0 +1,53 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Darari17/social-media/internal/storage"
+)
+
+func InitStorage() (storage.Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		baseURL := os.Getenv("STORAGE_BASE_URL")
+		if baseURL == "" {
+			baseURL = "/img"
+		}
+		signingKey := os.Getenv("STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			signingKey = os.Getenv("JWT_SECRET")
+		}
+		return storage.NewLocalStorage("public", "private", baseURL, os.Getenv("STORAGE_SIGNED_BASE_URL")+"/media", []byte(signingKey)), nil
+	case "s3":
+		return storage.NewS3Storage(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_PATH_STYLE") == "true",
+			os.Getenv("STORAGE_BASE_URL"),
+		)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
+
+// InitMediaGC reads how often unreferenced media is collected and how long it
+// is kept first. Collection is off when MEDIA_GC_INTERVAL is 0.
+func InitMediaGC() (interval, grace time.Duration, err error) {
+	interval, grace = time.Hour, 24*time.Hour
+	if v := os.Getenv("MEDIA_GC_INTERVAL"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid MEDIA_GC_INTERVAL: %w", err)
+		}
+	}
+	if v := os.Getenv("MEDIA_GC_GRACE"); v != "" {
+		if grace, err = time.ParseDuration(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid MEDIA_GC_GRACE: %w", err)
+		}
+	}
+	return interval, grace, nil
+}
@@ -0,0 +1,93 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/utils"
+)
+
+// InitUploadPolicies reads the upload policy of every kind of media from
+// UPLOAD_<KIND>_MAX_SIZE, UPLOAD_<KIND>_TYPES, UPLOAD_<KIND>_MAX_WIDTH and
+// UPLOAD_<KIND>_MAX_HEIGHT (KIND being AVATAR, POST or MESSAGE) and the
+// default storage quota from STORAGE_QUOTA. Unset values keep
+// utils.DefaultUploadPolicies.
+func InitUploadPolicies() (utils.UploadPolicies, error) {
+	policies := utils.UploadPolicies{
+		Kinds:        map[string]utils.UploadPolicy{},
+		DefaultQuota: utils.DefaultUploadPolicies.DefaultQuota,
+	}
+
+	for kind, policy := range utils.DefaultUploadPolicies.Kinds {
+		env := "UPLOAD_" + strings.ToUpper(kind) + "_"
+
+		if v := os.Getenv(env + "MAX_SIZE"); v != "" {
+			size, err := parseSize(v)
+			if err != nil {
+				return policies, fmt.Errorf("invalid %sMAX_SIZE: %w", env, err)
+			}
+			policy.MaxBytes = size
+		}
+
+		if v := os.Getenv(env + "TYPES"); v != "" {
+			policy.Types = nil
+			for _, t := range strings.Split(v, ",") {
+				t = strings.ToLower(strings.TrimSpace(t))
+				if !strings.Contains(t, "/") {
+					t = "image/" + t
+				}
+				if t == "image/jpg" {
+					t = "image/jpeg"
+				}
+				if !slices.Contains(utils.ImageTypes, t) {
+					return policies, fmt.Errorf("invalid %sTYPES: %s cannot be processed", env, t)
+				}
+				policy.Types = append(policy.Types, t)
+			}
+		}
+
+		for name, dim := range map[string]*int{"MAX_WIDTH": &policy.MaxWidth, "MAX_HEIGHT": &policy.MaxHeight} {
+			if v := os.Getenv(env + name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 {
+					return policies, fmt.Errorf("invalid %s%s %q", env, name, v)
+				}
+				*dim = n
+			}
+		}
+
+		policies.Kinds[kind] = policy
+	}
+
+	if v := os.Getenv("STORAGE_QUOTA"); v != "" {
+		quota, err := parseSize(v)
+		if err != nil {
+			return policies, fmt.Errorf("invalid STORAGE_QUOTA: %w", err)
+		}
+		policies.DefaultQuota = quota
+	}
+
+	return policies, nil
+}
+
+// parseSize reads a size in bytes, optionally followed by KB, MB or GB.
+func parseSize(v string) (int64, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	unit := int64(1)
+	for suffix, size := range map[string]int64{"KB": 1024, "MB": 1024 * 1024, "GB": 1024 * 1024 * 1024} {
+		if strings.HasSuffix(v, suffix) {
+			v, unit = strings.TrimSpace(strings.TrimSuffix(v, suffix)), size
+			break
+		}
+	}
+	v = strings.TrimSuffix(v, "B")
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a size", v)
+	}
+	return n * unit, nil
+}
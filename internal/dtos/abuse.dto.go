@@ -0,0 +1,20 @@
+package dtos
+
+import "time"
+
+type AbuseFlagResponse struct {
+	ID            int       `json:"id"`
+	Signal        string    `json:"signal"`
+	Count         int       `json:"count"`
+	WindowSeconds int       `json:"window_seconds"`
+	Details       *string   `json:"details"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// FlaggedAccountResponse is an account flagged by velocity rules with the
+// signals that triggered, newest first.
+type FlaggedAccountResponse struct {
+	UserID   int                 `json:"user_id"`
+	Username *string             `json:"username"`
+	Flags    []AbuseFlagResponse `json:"flags"`
+}
@@ -0,0 +1,29 @@
+package dtos
+
+import (
+	"time"
+)
+
+// BookmarkRequest saves a post, into a collection when CollectionID is set.
+type BookmarkRequest struct {
+	CollectionID *int `json:"collection_id"`
+}
+
+type BookmarkCollectionRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type BookmarkCollectionResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// bookmarks in the collection whose post can still be seen
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BookmarkResponse struct {
+	ID           int          `json:"id"`
+	CollectionID *int         `json:"collection_id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Post         PostResponse `json:"post"`
+}
@@ -13,10 +13,13 @@ type CommentUpdateRequest struct {
 }
 
 type CommentResponse struct {
-	ID        int        `json:"id"`
-	UserID    int        `json:"user_id"`
-	PostID    int        `json:"post_id"`
-	Content   string     `json:"content"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID      int    `json:"id"`
+	UserID  int    `json:"user_id"`
+	PostID  int    `json:"post_id"`
+	Content string `json:"content"`
+	// set when screening let the comment through behind a warning
+	ContentWarning *string           `json:"content_warning"`
+	Mentions       []MentionResponse `json:"mentions"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      *time.Time        `json:"updated_at"`
 }
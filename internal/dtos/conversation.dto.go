@@ -0,0 +1,62 @@
+package dtos
+
+import (
+	"mime/multipart"
+	"time"
+)
+
+type ConversationRequest struct {
+	MemberIDs []int   `json:"member_ids" binding:"required,min=1"`
+	Title     *string `json:"title"`
+}
+
+type MessageRequest struct {
+	Content string                `form:"content"`
+	Image   *multipart.FileHeader `form:"image"`
+}
+
+type MarkReadRequest struct {
+	MessageID *int `json:"message_id"`
+}
+
+type ConversationMemberResponse struct {
+	UserID            int     `json:"user_id"`
+	Name              *string `json:"name"`
+	Username          *string `json:"username"`
+	Avatar            *string `json:"avatar"`
+	LastReadMessageID *int    `json:"last_read_message_id"`
+}
+
+type MessageResponse struct {
+	ID             int     `json:"id"`
+	ConversationID int     `json:"conversation_id"`
+	SenderID       int     `json:"sender_id"`
+	Content        *string `json:"content"`
+	Image          *string `json:"image"`
+	// signed link to the image, valid for a limited time
+	ImageURL  *string   `json:"image_url"`
+	ReadBy    []int     `json:"read_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConversationResponse struct {
+	ID          int                          `json:"id"`
+	IsGroup     bool                         `json:"is_group"`
+	Title       *string                      `json:"title"`
+	Members     []ConversationMemberResponse `json:"members"`
+	LastMessage *MessageResponse             `json:"last_message"`
+	UnreadCount int                          `json:"unread_count"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	UpdatedAt   *time.Time                   `json:"updated_at"`
+}
+
+type MessageListResponse struct {
+	Messages   []MessageResponse `json:"messages"`
+	NextCursor *int              `json:"next_cursor"`
+}
+
+type MessageReadEvent struct {
+	ConversationID    int  `json:"conversation_id"`
+	UserID            int  `json:"user_id"`
+	LastReadMessageID *int `json:"last_read_message_id"`
+}
@@ -0,0 +1,27 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Event struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type NotificationEvent struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	ActorID   int       `json:"actor_id"`
+	PostID    *int      `json:"post_id"`
+	CommentID *int      `json:"comment_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PostStatsEvent struct {
+	PostID   int `json:"post_id"`
+	Likes    int `json:"likes"`
+	Comments int `json:"comments"`
+}
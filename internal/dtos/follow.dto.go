@@ -12,3 +12,9 @@ type FollowResponse struct {
 	FollowingID int       `json:"following_id"`
 	CreatedAt   time.Time `json:"created_at"`
 }
+
+type FollowRequestResponse struct {
+	ID        int          `json:"id"`
+	Requester UserResponse `json:"requester"`
+	CreatedAt time.Time    `json:"created_at"`
+}
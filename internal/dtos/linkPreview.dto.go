@@ -0,0 +1,10 @@
+package dtos
+
+// LinkPreviewResponse describes the page the first link of a post leads to.
+type LinkPreviewResponse struct {
+	URL         string  `json:"url"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	SiteName    *string `json:"site_name"`
+}
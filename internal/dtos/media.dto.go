@@ -0,0 +1,45 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/Darari17/social-media/internal/models"
+)
+
+// MediaUploadRequest starts a resumable upload of Size bytes.
+type MediaUploadRequest struct {
+	Size int64 `json:"size" binding:"required,min=1"`
+}
+
+type MediaUploadResponse struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Size   int64  `json:"size"`
+	// bytes received, the offset of the next chunk
+	Received  int64                `json:"received"`
+	Filename  *string              `json:"filename"`
+	Variants  models.ImageVariants `json:"variants"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+type UploadPolicyResponse struct {
+	Kind      string   `json:"kind"`
+	MaxBytes  int64    `json:"max_bytes"`
+	Types     []string `json:"types"`
+	MaxWidth  int      `json:"max_width"`
+	MaxHeight int      `json:"max_height"`
+}
+
+// StorageUsageResponse tells how much of their quota a user's media takes. An
+// image used in several places counts once in used_bytes.
+type StorageUsageResponse struct {
+	UsedBytes      int64                  `json:"used_bytes"`
+	QuotaBytes     int64                  `json:"quota_bytes"`
+	RemainingBytes int64                  `json:"remaining_bytes"`
+	PostsBytes     int64                  `json:"posts_bytes"`
+	AvatarBytes    int64                  `json:"avatar_bytes"`
+	MessagesBytes  int64                  `json:"messages_bytes"`
+	PendingBytes   int64                  `json:"pending_bytes"`
+	Policies       []UploadPolicyResponse `json:"policies"`
+}
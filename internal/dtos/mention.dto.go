@@ -0,0 +1,21 @@
+package dtos
+
+import "time"
+
+type MentionResponse struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+type MentionFeedResponse struct {
+	ID             int       `json:"id"`
+	PostID         int       `json:"post_id"`
+	CommentID      *int      `json:"comment_id"`
+	AuthorID       int       `json:"author_id"`
+	AuthorName     *string   `json:"author_name"`
+	AuthorUsername *string   `json:"author_username"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
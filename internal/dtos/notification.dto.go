@@ -0,0 +1,33 @@
+package dtos
+
+import "time"
+
+type NotificationActor struct {
+	ID       int     `json:"id"`
+	Name     *string `json:"name"`
+	Username *string `json:"username"`
+	Avatar   *string `json:"avatar"`
+}
+
+type NotificationResponse struct {
+	ID         int                 `json:"id"`
+	Type       string              `json:"type"`
+	PostID     *int                `json:"post_id"`
+	Actors     []NotificationActor `json:"actors"`
+	ActorCount int                 `json:"actor_count"`
+	Message    string              `json:"message"`
+	Read       bool                `json:"read"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	UnreadCount   int                    `json:"unread_count"`
+	Notifications []NotificationResponse `json:"notifications"`
+}
+
+type UnreadCountResponse struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+// NotificationPreferences maps a notification type to whether it is enabled.
+type NotificationPreferences map[string]bool
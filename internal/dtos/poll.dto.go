@@ -0,0 +1,30 @@
+package dtos
+
+import (
+	"time"
+)
+
+// PollVoteRequest is the one vote of a user: a single option, or several when
+// the poll allows multiple choices.
+type PollVoteRequest struct {
+	OptionIDs []int `json:"option_ids" binding:"required,min=1"`
+}
+
+type PollOptionResponse struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	// hidden until the viewer votes or the poll closes
+	Votes *int `json:"votes"`
+}
+
+type PollResponse struct {
+	ID       int  `json:"id"`
+	Multiple bool `json:"multiple"`
+	// hidden until the viewer votes or the poll closes
+	VotersCount *int                 `json:"voters_count"`
+	ExpiresAt   time.Time            `json:"expires_at"`
+	Closed      bool                 `json:"closed"`
+	Voted       bool                 `json:"voted"`
+	MyChoices   []int                `json:"my_choices"`
+	Options     []PollOptionResponse `json:"options"`
+}
@@ -1,26 +1,91 @@
 package dtos
 
 import (
-	"mime/multipart"
 	"time"
+
+	"github.com/Darari17/social-media/internal/models"
 )
 
+// PostRequest creates a post. MediaIDs are images uploaded beforehand through
+// POST /media, in gallery order. A draft is saved without being published and
+// a post with PublishAt is published at that time. PollOptions add a poll
+// open until PollExpiresAt.
 type PostRequest struct {
-	Content string                `form:"content"`
-	Image   *multipart.FileHeader `form:"image"`
+	Content    string     `form:"content"`
+	MediaIDs   []int      `form:"media_ids"`
+	AltTexts   []string   `form:"alt_texts"`
+	Visibility string     `form:"visibility"`
+	Draft      bool       `form:"draft"`
+	PublishAt  *time.Time `form:"publish_at" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	PollOptions   []string   `form:"poll_options"`
+	PollMultiple  bool       `form:"poll_multiple"`
+	PollExpiresAt *time.Time `form:"poll_expires_at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// PostScheduleRequest sets when a draft or scheduled post is published.
+type PostScheduleRequest struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
 }
 
+// PostUpdateRequest edits a post. Uploaded media_ids are appended to the
+// gallery with their alt_texts, remove_media drops images by id and
+// media_order lists the ids of the images kept in their new order.
 type PostUpdateRequest struct {
-	Content *string               `form:"content"`
-	Image   *multipart.FileHeader `form:"image"`
+	Content     *string  `form:"content"`
+	MediaIDs    []int    `form:"media_ids"`
+	AltTexts    []string `form:"alt_texts"`
+	RemoveMedia []int    `form:"remove_media"`
+	MediaOrder  []int    `form:"media_order"`
+	Visibility  *string  `form:"visibility"`
+}
+
+type PostMediaResponse struct {
+	ID       int                  `json:"id"`
+	Position int                  `json:"position"`
+	Filename string               `json:"filename"`
+	Variants models.ImageVariants `json:"variants"`
+	AltText  *string              `json:"alt_text"`
 }
 
 type PostResponse struct {
-	ID        int        `json:"id"`
-	UserID    int        `json:"user_id"`
-	Content   *string    `json:"content"`
-	Image     *string    `json:"image"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID      int     `json:"id"`
+	UserID  int     `json:"user_id"`
+	Content *string `json:"content"`
+	// the first image of the gallery, in every stored size
+	Image         *string              `json:"image"`
+	ImageVariants models.ImageVariants `json:"image_variants"`
+	Media         []PostMediaResponse  `json:"media"`
+	Visibility    string               `json:"visibility"`
+	// set when screening let the post through behind a warning
+	ContentWarning *string              `json:"content_warning"`
+	Mentions       []MentionResponse    `json:"mentions"`
+	Poll           *PollResponse        `json:"poll"`
+	LinkPreview    *LinkPreviewResponse `json:"link_preview"`
+	// set once the content or gallery was edited, see GET /posts/:id/revisions
+	Edited        bool `json:"edited"`
+	RevisionCount int  `json:"revision_count"`
+	// published, draft or scheduled
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// pinned to the top of the author's profile
+	Pinned bool `json:"pinned"`
+	// set when the viewer saved the post, see GET /bookmarks
+	BookmarkedByMe bool       `json:"bookmarked_by_me"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	// when a deleted post is purged for good
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// PostRevisionResponse is a version of a post that an edit replaced, shown
+// from created_at until replaced_at.
+type PostRevisionResponse struct {
+	Revision       int                 `json:"revision"`
+	Content        *string             `json:"content"`
+	ContentWarning *string             `json:"content_warning"`
+	Media          []PostMediaResponse `json:"media"`
+	CreatedAt      time.Time           `json:"created_at"`
+	ReplacedAt     time.Time           `json:"replaced_at"`
 }
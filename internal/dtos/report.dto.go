@@ -0,0 +1,64 @@
+package dtos
+
+import "time"
+
+type ReportRequest struct {
+	TargetType string  `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   int     `json:"target_id" binding:"required"`
+	Reason     string  `json:"reason" binding:"required"`
+	Details    *string `json:"details"`
+}
+
+type ReportResponse struct {
+	ID         int        `json:"id"`
+	ReporterID *int       `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int        `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Details    *string    `json:"details"`
+	Status     string     `json:"status"`
+	ResolvedBy *int       `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ModerationQueueItem is a report as seen by moderators, with the number of
+// open reports about the same target.
+type ModerationQueueItem struct {
+	ReportResponse
+	OpenReports int `json:"open_reports"`
+}
+
+type ReportFilter struct {
+	Status     string `form:"status"`
+	TargetType string `form:"target_type"`
+	Reason     string `form:"reason"`
+}
+
+type ResolveReportRequest struct {
+	Status string  `json:"status" binding:"required,oneof=actioned dismissed"`
+	Action *string `json:"action"`
+	Reason string  `json:"reason" binding:"required"`
+	// only used by the suspend action, empty means permanent
+	DurationHours *int `json:"duration_hours" binding:"omitempty,min=1"`
+}
+
+type ModerationActionRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   int    `json:"target_id" binding:"required"`
+	Action     string `json:"action" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	// only used by the suspend action, empty means permanent
+	DurationHours *int `json:"duration_hours" binding:"omitempty,min=1"`
+}
+
+type ModerationActionResponse struct {
+	ID          int       `json:"id"`
+	ModeratorID int       `json:"moderator_id"`
+	Action      string    `json:"action"`
+	TargetType  string    `json:"target_type"`
+	TargetID    int       `json:"target_id"`
+	ReportID    *int      `json:"report_id"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package dtos
+
+import "time"
+
+type ScreeningRuleRequest struct {
+	Kind    string `json:"kind" binding:"required,oneof=word regex domain"`
+	Pattern string `json:"pattern" binding:"required,max=500"`
+	Outcome string `json:"outcome" binding:"required,oneof=warn hold reject"`
+}
+
+type ScreeningRuleResponse struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Outcome   string    `json:"outcome"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MutedWordRequest struct {
+	Word string `json:"word" binding:"required,max=100"`
+}
+
+type MutedWordResponse struct {
+	ID        int       `json:"id"`
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
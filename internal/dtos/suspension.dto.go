@@ -0,0 +1,37 @@
+package dtos
+
+import "time"
+
+type SuspendRequest struct {
+	// leave empty for a permanent suspension
+	DurationHours *int   `json:"duration_hours" binding:"omitempty,min=1"`
+	Reason        string `json:"reason" binding:"required"`
+}
+
+type SuspensionResponse struct {
+	UserID         int        `json:"user_id"`
+	SuspendedAt    time.Time  `json:"suspended_at"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	Reason         *string    `json:"reason"`
+}
+
+type AppealRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Message  string `json:"message" binding:"required,max=2000"`
+}
+
+type AppealResponse struct {
+	ID         int                 `json:"id"`
+	UserID     int                 `json:"user_id"`
+	Message    string              `json:"message"`
+	Status     string              `json:"status"`
+	ReviewedBy *int                `json:"reviewed_by"`
+	ReviewedAt *time.Time          `json:"reviewed_at"`
+	CreatedAt  time.Time           `json:"created_at"`
+	Suspension *SuspensionResponse `json:"suspension,omitempty"`
+}
+
+type ResolveAppealRequest struct {
+	Status string `json:"status" binding:"required,oneof=accepted rejected"`
+}
@@ -3,6 +3,8 @@ package dtos
 import (
 	"mime/multipart"
 	"time"
+
+	"github.com/Darari17/social-media/internal/models"
 )
 
 type UserRequest struct {
@@ -11,9 +13,11 @@ type UserRequest struct {
 }
 
 type UserUpdateRequest struct {
-	Name   *string               `json:"name" form:"name"`
-	Avatar *multipart.FileHeader `form:"avatar"`
-	Bio    *string               `json:"bio" form:"bio"`
+	Name      *string               `json:"name" form:"name"`
+	Username  *string               `json:"username" form:"username"`
+	Avatar    *multipart.FileHeader `form:"avatar"`
+	Bio       *string               `json:"bio" form:"bio"`
+	IsPrivate *bool                 `json:"is_private" form:"is_private"`
 }
 
 type UserTokenResponse struct {
@@ -21,11 +25,15 @@ type UserTokenResponse struct {
 }
 
 type UserResponse struct {
-	ID        int        `json:"id"`
-	Name      *string    `json:"name"`
-	Email     *string    `json:"email"`
-	Avatar    *string    `json:"avatar"`
-	Bio       *string    `json:"bio"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID       int     `json:"id"`
+	Name     *string `json:"name"`
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	Avatar   *string `json:"avatar"`
+	// the avatar in every stored size, keyed by size name
+	AvatarVariants models.ImageVariants `json:"avatar_variants"`
+	Bio            *string              `json:"bio"`
+	IsPrivate      bool                 `json:"is_private"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      *time.Time           `json:"updated_at"`
 }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type AbuseHandler struct {
+	velocityRepo *repos.VelocityRepo
+}
+
+func NewAbuseHandler(vr *repos.VelocityRepo) *AbuseHandler {
+	return &AbuseHandler{velocityRepo: vr}
+}
+
+// GetFlags godoc
+// @Summary Get flagged accounts
+// @Description Get accounts flagged by velocity rules (too many follows or likes, following and unfollowing the same user over and over), most recently flagged first, with the signals that flagged them. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.FlaggedAccountResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Router /admin/flags [get]
+func (ah *AbuseHandler) GetFlags(c *gin.Context) {
+	limit, offset := utils.GetPagination(c)
+	accounts, err := ah.velocityRepo.GetFlaggedAccounts(c.Request.Context(), limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch flagged accounts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get flagged accounts successfully",
+		Data:    accounts,
+	})
+}
+
+// checkVelocity tracks the signal for the user and writes the response when a
+// velocity rule stops the request: 429 when throttled, 401 with the session
+// ended when the user has to log in again. Tracking errors let the request
+// through.
+func checkVelocity(c *gin.Context, vr *repos.VelocityRepo, userId int, signal, subject string) bool {
+	verdict, err := vr.Track(c.Request.Context(), userId, signal, subject)
+	if err != nil {
+		log.Println(err.Error())
+		return true
+	}
+
+	switch verdict {
+	case models.VelocityThrottle:
+		c.JSON(http.StatusTooManyRequests, dtos.Response{
+			Code:    http.StatusTooManyRequests,
+			Success: false,
+			Message: "You are doing this too often, please slow down",
+		})
+		return false
+	case models.VelocityReauth:
+		token, _, err := utils.GetTokenFromCtx(c)
+		if err == nil {
+			err = vr.EndSession(c.Request.Context(), token)
+		}
+		if err != nil {
+			log.Println(err.Error())
+		}
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unusual activity detected, please log in again",
+		})
+		return false
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -9,15 +10,18 @@ import (
 	"github.com/Darari17/social-media/internal/repos"
 	"github.com/Darari17/social-media/pkg"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 type AuthHandler struct {
-	authRepo *repos.AuthRepo
+	authRepo       *repos.AuthRepo
+	suspensionRepo *repos.SuspensionRepo
 }
 
-func NewAuthHandler(authRepo *repos.AuthRepo) *AuthHandler {
+func NewAuthHandler(authRepo *repos.AuthRepo, sr *repos.SuspensionRepo) *AuthHandler {
 	return &AuthHandler{
-		authRepo: authRepo,
+		authRepo:       authRepo,
+		suspensionRepo: sr,
 	}
 }
 
@@ -31,6 +35,7 @@ func NewAuthHandler(authRepo *repos.AuthRepo) *AuthHandler {
 // @Success 201 {object} dtos.Response
 // @Failure 400 {object} dtos.Response
 // @Failure 409 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /auth/register [post]
 func (ah *AuthHandler) Register(c *gin.Context) {
 	var body dtos.UserRequest
@@ -86,7 +91,9 @@ func (ah *AuthHandler) Register(c *gin.Context) {
 // @Param request body dtos.UserRequest true "Login request"
 // @Success 200 {object} dtos.Response{data=dtos.UserTokenResponse}
 // @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response{data=dtos.SuspensionResponse}
 // @Failure 500 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /auth/login [post]
 func (ah *AuthHandler) Login(c *gin.Context) {
 	var body dtos.UserRequest
@@ -130,6 +137,26 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	suspension, err := ah.suspensionRepo.GetSuspension(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Something went wrong",
+		})
+		return
+	}
+	if suspension != nil {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "Your account is suspended",
+			Data:    suspension,
+		})
+		return
+	}
+
 	claim := pkg.NewJWTClaims(user.ID)
 	token, err := claim.GenerateToken()
 	if err != nil {
@@ -181,3 +208,102 @@ func (ah *AuthHandler) Logout(c *gin.Context) {
 		Message: "Logout Succesfully",
 	})
 }
+
+// Appeal godoc
+// @Summary Appeal suspension
+// @Description Ask the moderators to lift a suspension. Suspended users cannot log in, so the appeal is sent with the account credentials. Only one appeal can wait for review at a time.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.AppealRequest true "Appeal request"
+// @Success 201 {object} dtos.Response{data=dtos.AppealResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /auth/appeal [post]
+func (ah *AuthHandler) Appeal(c *gin.Context) {
+	var body dtos.AppealRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body request",
+		})
+		return
+	}
+
+	user, err := ah.authRepo.GetEmail(c.Request.Context(), body.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Something went wrong",
+		})
+		return
+	}
+	if user == nil || !pkg.VerifyPassword(user.Password, body.Password) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid Email or Password",
+		})
+		return
+	}
+
+	suspension, err := ah.suspensionRepo.GetSuspension(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Something went wrong",
+		})
+		return
+	}
+	if suspension == nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Your account is not suspended",
+		})
+		return
+	}
+
+	appeal := models.Appeal{
+		UserID:  user.ID,
+		Message: body.Message,
+	}
+	if err := ah.suspensionRepo.CreateAppeal(c.Request.Context(), &appeal); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "Your previous appeal is still being reviewed",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to send appeal",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Appeal sent successfully",
+		Data: dtos.AppealResponse{
+			ID:         appeal.ID,
+			UserID:     appeal.UserID,
+			Message:    appeal.Message,
+			Status:     appeal.Status,
+			CreatedAt:  appeal.CreatedAt,
+			Suspension: suspension,
+		},
+	})
+}
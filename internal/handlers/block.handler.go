@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type BlockHandler struct {
+	blockRepo *repos.BlockRepo
+	userRepo  *repos.UserRepo
+}
+
+func NewBlockHandler(br *repos.BlockRepo, ur *repos.UserRepo) *BlockHandler {
+	return &BlockHandler{
+		blockRepo: br,
+		userRepo:  ur,
+	}
+}
+
+// BlockUser godoc
+// @Summary Block user
+// @Description Block a user. Both users stop following each other and no longer see each other's posts, comments and likes; they cannot follow, mention or message each other.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID to block"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/{id}/block [post]
+func (bh *BlockHandler) BlockUser(c *gin.Context) {
+	userId, targetId, ok := targetFromCtx(c, bh.userRepo, "You cannot block yourself")
+	if !ok {
+		return
+	}
+
+	if err := bh.blockRepo.BlockUser(c.Request.Context(), userId, targetId); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to block user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Blocked user successfully",
+	})
+}
+
+// UnblockUser godoc
+// @Summary Unblock user
+// @Description Unblock a user. Follows removed by the block are not restored.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID to unblock"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/{id}/block [delete]
+func (bh *BlockHandler) UnblockUser(c *gin.Context) {
+	userId, targetId, ok := targetFromCtx(c, bh.userRepo, "You cannot unblock yourself")
+	if !ok {
+		return
+	}
+
+	rows, err := bh.blockRepo.UnblockUser(c.Request.Context(), userId, targetId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to unblock user",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "You have not blocked this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Unblocked user successfully",
+	})
+}
+
+// MuteUser godoc
+// @Summary Mute user
+// @Description Mute a user. Their posts, comments, mentions and notifications are hidden from you; they are not told and still see your content.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID to mute"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/{id}/mute [post]
+func (bh *BlockHandler) MuteUser(c *gin.Context) {
+	userId, targetId, ok := targetFromCtx(c, bh.userRepo, "You cannot mute yourself")
+	if !ok {
+		return
+	}
+
+	if err := bh.blockRepo.MuteUser(c.Request.Context(), userId, targetId); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to mute user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Muted user successfully",
+	})
+}
+
+// UnmuteUser godoc
+// @Summary Unmute user
+// @Description Unmute a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID to unmute"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/{id}/mute [delete]
+func (bh *BlockHandler) UnmuteUser(c *gin.Context) {
+	userId, targetId, ok := targetFromCtx(c, bh.userRepo, "You cannot unmute yourself")
+	if !ok {
+		return
+	}
+
+	rows, err := bh.blockRepo.UnmuteUser(c.Request.Context(), userId, targetId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to unmute user",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "You have not muted this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Unmuted user successfully",
+	})
+}
+
+// GetBlockedUsers godoc
+// @Summary Get blocked users
+// @Description Get users blocked by the authenticated user
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.UserResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /users/blocks [get]
+func (bh *BlockHandler) GetBlockedUsers(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	users, err := bh.blockRepo.GetBlockedUsers(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch blocked users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get blocked users successfully",
+		Data:    users,
+	})
+}
+
+// GetMutedUsers godoc
+// @Summary Get muted users
+// @Description Get users muted by the authenticated user
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.UserResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /users/mutes [get]
+func (bh *BlockHandler) GetMutedUsers(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	users, err := bh.blockRepo.GetMutedUsers(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch muted users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get muted users successfully",
+		Data:    users,
+	})
+}
+
+// targetFromCtx reads the target user id from the path and makes sure that
+// user exists and is not the authenticated user. It writes the error
+// response itself.
+func targetFromCtx(c *gin.Context, ur *repos.UserRepo, selfMessage string) (int, int, bool) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return 0, 0, false
+	}
+
+	targetId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid user id",
+		})
+		return 0, 0, false
+	}
+
+	if targetId == userId {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: selfMessage,
+		})
+		return 0, 0, false
+	}
+
+	if _, err := ur.GetUserByID(c.Request.Context(), targetId); err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "User not found",
+		})
+		return 0, 0, false
+	}
+
+	return userId, targetId, true
+}
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type BookmarkHandler struct {
+	bookmarkRepo *repos.BookmarkRepo
+	postRepo     *repos.PostRepo
+}
+
+func NewBookmarkHandler(bookmarkRepo *repos.BookmarkRepo, postRepo *repos.PostRepo) *BookmarkHandler {
+	return &BookmarkHandler{
+		bookmarkRepo: bookmarkRepo,
+		postRepo:     postRepo,
+	}
+}
+
+// BookmarkPost godoc
+// @Summary Bookmark post
+// @Description Save a post for later, privately. With a collection_id the bookmark goes into that collection; bookmarking a saved post again moves it.
+// @Tags Bookmarks
+// @Accept json
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param body body dtos.BookmarkRequest false "Collection to save the post in"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.BookmarkResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/bookmark [post]
+func (h *BookmarkHandler) BookmarkPost(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	postId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post ID",
+		})
+		return
+	}
+
+	// the body is optional, a bare request saves the post outside collections
+	var req dtos.BookmarkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Invalid body",
+			})
+			return
+		}
+	}
+
+	post, err := h.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	if err != nil || post.Status != models.PostPublished {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	bookmark := models.Bookmark{
+		UserID:       userId,
+		PostID:       postId,
+		CollectionID: req.CollectionID,
+	}
+	if err := h.bookmarkRepo.SaveBookmark(c.Request.Context(), &bookmark); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Collection not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to bookmark post",
+		})
+		return
+	}
+
+	post.BookmarkedByMe = true
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Post bookmarked successfully",
+		Data: dtos.BookmarkResponse{
+			ID:           bookmark.ID,
+			CollectionID: bookmark.CollectionID,
+			CreatedAt:    bookmark.CreatedAt,
+			Post:         *post,
+		},
+	})
+}
+
+// UnbookmarkPost godoc
+// @Summary Remove bookmark
+// @Description Remove a post from the user's bookmarks
+// @Tags Bookmarks
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/bookmark [delete]
+func (h *BookmarkHandler) UnbookmarkPost(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	postId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post ID",
+		})
+		return
+	}
+
+	rows, err := h.bookmarkRepo.DeleteBookmark(c.Request.Context(), userId, postId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to remove bookmark",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Bookmark not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Bookmark removed successfully",
+	})
+}
+
+// GetBookmarks godoc
+// @Summary Get bookmarks
+// @Description Get the user's bookmarks, most recent first. Bookmarks of posts that were deleted or can no longer be seen are left out.
+// @Tags Bookmarks
+// @Produce json
+// @Param collection_id query int false "Only bookmarks in this collection"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.BookmarkResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Router /bookmarks [get]
+func (h *BookmarkHandler) GetBookmarks(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var collectionId *int
+	if v := c.Query("collection_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Invalid collection id",
+			})
+			return
+		}
+		collectionId = &id
+	}
+
+	limit, offset := utils.GetPagination(c)
+	bookmarks, err := h.bookmarkRepo.GetBookmarks(c.Request.Context(), userId, collectionId, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch bookmarks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get bookmarks successfully",
+		Data:    bookmarks,
+	})
+}
+
+// GetCollections godoc
+// @Summary Get bookmark collections
+// @Description Get the user's bookmark collections by name
+// @Tags Bookmarks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.BookmarkCollectionResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /bookmarks/collections [get]
+func (h *BookmarkHandler) GetCollections(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	collections, err := h.bookmarkRepo.GetCollections(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch collections",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get collections successfully",
+		Data:    collections,
+	})
+}
+
+// CreateCollection godoc
+// @Summary Create bookmark collection
+// @Description Add a named collection to sort bookmarks into. Names are unique per user, ignoring case.
+// @Tags Bookmarks
+// @Accept json
+// @Produce json
+// @Param body body dtos.BookmarkCollectionRequest true "Collection name"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.BookmarkCollectionResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /bookmarks/collections [post]
+func (h *BookmarkHandler) CreateCollection(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var req dtos.BookmarkCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body",
+		})
+		return
+	}
+
+	collection := models.BookmarkCollection{
+		UserID: userId,
+		Name:   strings.TrimSpace(req.Name),
+	}
+	if err := h.bookmarkRepo.CreateCollection(c.Request.Context(), &collection); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "You already have a collection with this name",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create collection",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Collection created successfully",
+		Data: dtos.BookmarkCollectionResponse{
+			ID:        collection.ID,
+			Name:      collection.Name,
+			CreatedAt: collection.CreatedAt,
+		},
+	})
+}
+
+// DeleteCollection godoc
+// @Summary Delete bookmark collection
+// @Description Delete a bookmark collection. Its bookmarks are kept outside of any collection.
+// @Tags Bookmarks
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /bookmarks/collections/{id} [delete]
+func (h *BookmarkHandler) DeleteCollection(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	collectionId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid collection id",
+		})
+		return
+	}
+
+	rows, err := h.bookmarkRepo.DeleteCollection(c.Request.Context(), userId, collectionId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to delete collection",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Collection not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Collection deleted successfully",
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type CloseFriendHandler struct {
+	closeFriendRepo *repos.CloseFriendRepo
+	userRepo        *repos.UserRepo
+	blockRepo       *repos.BlockRepo
+}
+
+func NewCloseFriendHandler(cr *repos.CloseFriendRepo, ur *repos.UserRepo, br *repos.BlockRepo) *CloseFriendHandler {
+	return &CloseFriendHandler{
+		closeFriendRepo: cr,
+		userRepo:        ur,
+		blockRepo:       br,
+	}
+}
+
+// GetCloseFriends godoc
+// @Summary Get close friends
+// @Description Get the authenticated user's close friends, the audience of close_friends posts
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.UserResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /users/profile/close-friends [get]
+func (ch *CloseFriendHandler) GetCloseFriends(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	users, err := ch.closeFriendRepo.GetCloseFriends(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch close friends",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get close friends successfully",
+		Data:    users,
+	})
+}
+
+// AddCloseFriend godoc
+// @Summary Add close friend
+// @Description Add a user to the authenticated user's close friends
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/profile/close-friends/{id} [post]
+func (ch *CloseFriendHandler) AddCloseFriend(c *gin.Context) {
+	userId, friendId, ok := targetFromCtx(c, ch.userRepo, "You cannot add yourself")
+	if !ok {
+		return
+	}
+
+	blocked, err := ch.blockRepo.IsBlocked(c.Request.Context(), userId, friendId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to add close friend",
+		})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You cannot add this user",
+		})
+		return
+	}
+
+	if err := ch.closeFriendRepo.AddCloseFriend(c.Request.Context(), userId, friendId); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to add close friend",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Close friend added",
+	})
+}
+
+// RemoveCloseFriend godoc
+// @Summary Remove close friend
+// @Description Remove a user from the authenticated user's close friends
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/profile/close-friends/{id} [delete]
+func (ch *CloseFriendHandler) RemoveCloseFriend(c *gin.Context) {
+	userId, friendId, ok := targetFromCtx(c, ch.userRepo, "You cannot remove yourself")
+	if !ok {
+		return
+	}
+
+	rows, err := ch.closeFriendRepo.RemoveCloseFriend(c.Request.Context(), userId, friendId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to remove close friend",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "User is not in your close friends",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Close friend removed",
+	})
+}
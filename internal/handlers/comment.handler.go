@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,25 +13,41 @@ import (
 )
 
 type CommentHandler struct {
-	repo     *repos.CommentRepo
-	postRepo *repos.PostRepo
+	repo             *repos.CommentRepo
+	postRepo         *repos.PostRepo
+	mentionRepo      *repos.MentionRepo
+	notificationRepo *repos.NotificationRepo
+	eventRepo        *repos.EventRepo
+	reportRepo       *repos.ReportRepo
+	screener         utils.Screener
 }
 
-func NewCommentHandler(r *repos.CommentRepo, p *repos.PostRepo) *CommentHandler {
-	return &CommentHandler{repo: r, postRepo: p}
+func NewCommentHandler(r *repos.CommentRepo, p *repos.PostRepo, m *repos.MentionRepo, n *repos.NotificationRepo, e *repos.EventRepo, rr *repos.ReportRepo, s utils.Screener) *CommentHandler {
+	return &CommentHandler{
+		repo:             r,
+		postRepo:         p,
+		mentionRepo:      m,
+		notificationRepo: n,
+		eventRepo:        e,
+		reportRepo:       rr,
+		screener:         s,
+	}
 }
 
 // CreateComment godoc
 // @Summary Post comment
-// @Description Create a new comment on a post
+// @Description Create a new comment on a post. The content is screened first: it may be refused, published behind a content warning, or held hidden until a moderator reviews it (202).
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
 // @Param request body dtos.CommentRequest true "Comment body"
 // @Security BearerAuth
-// @Success 201 {object} dtos.Response
+// @Success 201 {object} dtos.Response{data=dtos.CommentResponse}
+// @Success 202 {object} dtos.Response{data=dtos.CommentResponse}
 // @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /posts/{id}/comments [post]
 func (h *CommentHandler) CreateComment(c *gin.Context) {
 	userId, err := utils.GetUserFromCtx(c)
@@ -63,11 +80,27 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
+	post, err := h.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	screened, ok := screenContent(c, h.screener, body.Content)
+	if !ok {
+		return
+	}
+
 	comment := models.Comment{
 		UserID:  userId,
 		PostID:  postId,
 		Content: body.Content,
 	}
+	comment.ContentWarning, comment.HiddenAt = screenedFields(screened)
 
 	if err := h.repo.CreateComment(c.Request.Context(), &comment); err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
@@ -78,10 +111,59 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
+	mentions, mentioned, err := h.mentionRepo.SaveMentions(c.Request.Context(), postId, &comment.ID, userId, comment.Content)
+	if err != nil {
+		log.Println("Failed to save mentions.\nCause:", err.Error())
+		mentions = []dtos.MentionResponse{}
+	}
+
+	response := dtos.CommentResponse{
+		ID:             comment.ID,
+		UserID:         comment.UserID,
+		PostID:         comment.PostID,
+		Content:        comment.Content,
+		ContentWarning: comment.ContentWarning,
+		Mentions:       mentions,
+		CreatedAt:      comment.CreatedAt,
+	}
+
+	// nobody is told about a held comment until a moderator lets it through
+	if comment.HiddenAt != nil {
+		holdForReview(c, h.reportRepo, models.ReportTargetComment, comment.ID, screened)
+		c.JSON(http.StatusAccepted, dtos.Response{
+			Code:    http.StatusAccepted,
+			Success: true,
+			Message: "Comment is held for review",
+			Data:    response,
+		})
+		return
+	}
+
+	if err == nil {
+		notifyMentions(c.Request.Context(), h.notificationRepo, userId, postId, &comment.ID, mentioned)
+	}
+
+	// an author mentioned in the comment was already notified about it
+	ownerMentioned := false
+	for _, id := range mentioned {
+		ownerMentioned = ownerMentioned || id == post.UserID
+	}
+	if !ownerMentioned {
+		notify(c.Request.Context(), h.notificationRepo, models.Notification{
+			UserID:    post.UserID,
+			ActorID:   userId,
+			Type:      models.NotificationComment,
+			PostID:    &postId,
+			CommentID: &comment.ID,
+		})
+	}
+	h.eventRepo.PublishPostStats(postId)
+
 	c.JSON(http.StatusCreated, dtos.Response{
 		Code:    http.StatusCreated,
 		Success: true,
 		Message: "Comment added",
+		Data:    response,
 	})
 }
 
@@ -94,8 +176,11 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=[]models.Comment}
 // @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /posts/{id}/comments [get]
 func (h *CommentHandler) GetComments(c *gin.Context) {
+	viewerId, _ := utils.GetUserFromCtx(c)
+
 	postId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dtos.Response{
@@ -106,7 +191,17 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
-	comments, err := h.repo.GetCommentsByPost(c.Request.Context(), postId)
+	// the post itself may be hidden from the viewer
+	if _, err := h.postRepo.GetPostByID(c.Request.Context(), postId, viewerId); err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	comments, err := h.repo.GetCommentsByPost(c.Request.Context(), postId, viewerId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
@@ -126,7 +221,7 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 
 // UpdateComment godoc
 // @Summary Update comment
-// @Description Update a comment by ID
+// @Description Update a comment by ID. The new content is screened like on creation; held content is hidden until a moderator reviews it (202).
 // @Tags Comments
 // @Accept json
 // @Produce json
@@ -134,9 +229,23 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 // @Param request body dtos.CommentUpdateRequest true "Comment update body"
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response
+// @Success 202 {object} dtos.Response
 // @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /posts/comments/{id} [put]
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
 	commentId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dtos.Response{
@@ -157,7 +266,35 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.UpdateComment(c.Request.Context(), commentId, body.Content); err != nil {
+	comment, err := h.repo.GetCommentByID(c.Request.Context(), commentId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Comment not found",
+		})
+		return
+	}
+
+	if comment.UserID != userId {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You are not allowed to update this comment",
+		})
+		return
+	}
+
+	screened, ok := screenContent(c, h.screener, body.Content)
+	if !ok {
+		return
+	}
+
+	comment.Content = body.Content
+	comment.ContentWarning, comment.HiddenAt = screenedFields(screened)
+	held := comment.HiddenAt != nil
+
+	if err := h.repo.UpdateComment(c.Request.Context(), comment); err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
 			Success: false,
@@ -166,6 +303,24 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
+	// offsets shift when the text changes, so mentions are always re-saved
+	_, mentioned, err := h.mentionRepo.SaveMentions(c.Request.Context(), comment.PostID, &comment.ID, comment.UserID, body.Content)
+	if err != nil {
+		log.Println("Failed to save mentions.\nCause:", err.Error())
+	} else if !held {
+		notifyMentions(c.Request.Context(), h.notificationRepo, comment.UserID, comment.PostID, &comment.ID, mentioned)
+	}
+
+	if held {
+		holdForReview(c, h.reportRepo, models.ReportTargetComment, comment.ID, screened)
+		c.JSON(http.StatusAccepted, dtos.Response{
+			Code:    http.StatusAccepted,
+			Success: true,
+			Message: "Comment is held for review",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
 		Success: true,
@@ -182,8 +337,21 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response
 // @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /posts/comments/{id} [delete]
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
 	commentId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dtos.Response{
@@ -194,6 +362,25 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
+	comment, err := h.repo.GetCommentByID(c.Request.Context(), commentId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Comment not found",
+		})
+		return
+	}
+
+	if comment.UserID != userId {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You are not allowed to delete this comment",
+		})
+		return
+	}
+
 	if err := h.repo.DeleteComment(c.Request.Context(), commentId); err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
@@ -202,6 +389,7 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		})
 		return
 	}
+	h.eventRepo.PublishPostStats(comment.PostID)
 
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxConversationMembers caps group conversations, the creator included.
+const maxConversationMembers = 10
+
+type ConversationHandler struct {
+	conversationRepo *repos.ConversationRepo
+	eventRepo        *repos.EventRepo
+	blockRepo        *repos.BlockRepo
+	followRepo       *repos.FollowRepo
+	store            storage.Storage
+	mediaRepo        *repos.MediaRepo
+	policies         utils.UploadPolicies
+}
+
+func NewConversationHandler(cr *repos.ConversationRepo, er *repos.EventRepo, br *repos.BlockRepo, fr *repos.FollowRepo, store storage.Storage, mr *repos.MediaRepo, policies utils.UploadPolicies) *ConversationHandler {
+	return &ConversationHandler{
+		conversationRepo: cr,
+		eventRepo:        er,
+		blockRepo:        br,
+		followRepo:       fr,
+		store:            store,
+		mediaRepo:        mr,
+		policies:         policies,
+	}
+}
+
+// CreateConversation godoc
+// @Summary Start conversation
+// @Description Start a 1:1 conversation (one member) or a small group (several members). An existing 1:1 conversation is returned instead of creating a duplicate. Private accounts can only be added by their followers.
+// @Tags Conversations
+// @Accept json
+// @Produce json
+// @Param request body dtos.ConversationRequest true "Conversation members"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.ConversationResponse}
+// @Success 200 {object} dtos.Response{data=dtos.ConversationResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /conversations [post]
+func (ch *ConversationHandler) CreateConversation(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var body dtos.ConversationRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body",
+		})
+		return
+	}
+
+	others := []int{}
+	seen := map[int]bool{userId: true}
+	for _, id := range body.MemberIDs {
+		if !seen[id] {
+			seen[id] = true
+			others = append(others, id)
+		}
+	}
+
+	if len(others) == 0 {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Add at least one other member",
+		})
+		return
+	}
+	if len(others)+1 > maxConversationMembers {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Group conversations are limited to " + strconv.Itoa(maxConversationMembers) + " members",
+		})
+		return
+	}
+
+	count, err := ch.conversationRepo.CountUsers(c.Request.Context(), others)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create conversation",
+		})
+		return
+	}
+	if count != len(others) {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "User not found",
+		})
+		return
+	}
+
+	blocked, err := ch.blockRepo.AnyBlocked(c.Request.Context(), userId, others)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create conversation",
+		})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You cannot message this user",
+		})
+		return
+	}
+
+	// private accounts only receive new conversations from their followers
+	restricted, err := ch.followRepo.AnyPrivateNotFollowed(c.Request.Context(), userId, others)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create conversation",
+		})
+		return
+	}
+	if restricted {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "This account only accepts messages from followers",
+		})
+		return
+	}
+
+	if len(others) == 1 {
+		existingId, err := ch.conversationRepo.FindDirectConversation(c.Request.Context(), userId, others[0])
+		if err != nil {
+			log.Println(err.Error())
+			c.JSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Failed to create conversation",
+			})
+			return
+		}
+		if existingId != nil {
+			existing, err := ch.conversationRepo.GetConversationByID(c.Request.Context(), *existingId)
+			if err != nil {
+				log.Println(err.Error())
+				c.JSON(http.StatusInternalServerError, dtos.Response{
+					Code:    http.StatusInternalServerError,
+					Success: false,
+					Message: "Failed to create conversation",
+				})
+				return
+			}
+			c.JSON(http.StatusOK, dtos.Response{
+				Code:    http.StatusOK,
+				Success: true,
+				Message: "Conversation already exists",
+				Data:    existing,
+			})
+			return
+		}
+	}
+
+	conv := models.Conversation{
+		IsGroup:   len(others) > 1,
+		CreatedBy: userId,
+	}
+	if conv.IsGroup && body.Title != nil && strings.TrimSpace(*body.Title) != "" {
+		title := strings.TrimSpace(*body.Title)
+		conv.Title = &title
+	}
+
+	if err := ch.conversationRepo.CreateConversation(c.Request.Context(), &conv, append([]int{userId}, others...)); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create conversation",
+		})
+		return
+	}
+
+	created, err := ch.conversationRepo.GetConversationByID(c.Request.Context(), conv.ID)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Conversation created",
+		Data:    created,
+	})
+}
+
+// GetConversations godoc
+// @Summary Get conversations
+// @Description Get the authenticated user's conversations with their last message and unread count
+// @Tags Conversations
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.ConversationResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /conversations [get]
+func (ch *ConversationHandler) GetConversations(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	limit, offset := utils.GetPagination(c)
+	conversations, err := ch.conversationRepo.GetConversations(c.Request.Context(), userId, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch conversations",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get conversations successfully",
+		Data:    conversations,
+	})
+}
+
+// GetMessages godoc
+// @Summary Get messages
+// @Description Get messages of a conversation, newest first. Pass next_cursor from the previous page as ?before= to load older messages.
+// @Tags Conversations
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param before query int false "Only messages with a smaller id"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.MessageListResponse}
+// @Failure 404 {object} dtos.Response
+// @Router /conversations/{id}/messages [get]
+func (ch *ConversationHandler) GetMessages(c *gin.Context) {
+	_, conversationId, ok := ch.memberFromCtx(c)
+	if !ok {
+		return
+	}
+
+	var before *int
+	if s := c.Query("before"); s != "" {
+		cursor, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		before = &cursor
+	}
+
+	limit, _ := utils.GetPagination(c)
+	messages, err := ch.conversationRepo.GetMessages(c.Request.Context(), conversationId, before, limit)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch messages",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get messages successfully",
+		Data:    messages,
+	})
+}
+
+// SendMessage godoc
+// @Summary Send message
+// @Description Send a text message, an image, or both to a conversation
+// @Tags Conversations
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param content formData string false "Message text"
+// @Param image formData file false "Image attachment within the message image policy (see GET /users/profile/storage)"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.MessageResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 413 {object} dtos.Response
+// @Failure 415 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /conversations/{id}/messages [post]
+func (ch *ConversationHandler) SendMessage(c *gin.Context) {
+	userId, conversationId, ok := ch.memberFromCtx(c)
+	if !ok {
+		return
+	}
+
+	if !ch.canSend(c, conversationId, userId) {
+		return
+	}
+
+	var body dtos.MessageRequest
+	if err := c.ShouldBind(&body); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid form data",
+		})
+		return
+	}
+
+	content := strings.TrimSpace(body.Content)
+	if content == "" && body.Image == nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Message cannot be empty",
+		})
+		return
+	}
+
+	msg := models.Message{
+		ConversationID: conversationId,
+		SenderID:       userId,
+	}
+	if content != "" {
+		msg.Content = &content
+	}
+
+	if body.Image != nil {
+		if !checkQuota(c, ch.mediaRepo, ch.policies, userId, body.Image.Size) {
+			return
+		}
+		variants, err := utils.FileUpload(c, ch.store, ch.mediaRepo, body.Image, ch.policies.Policy(models.MediaMessage))
+		if err != nil {
+			uploadFailed(c, err)
+			return
+		}
+		filename := variants[models.ImageFull].Filename
+		msg.Image = &filename
+	}
+
+	if err := ch.conversationRepo.CreateMessage(c.Request.Context(), &msg); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to send message",
+		})
+		return
+	}
+
+	response := dtos.MessageResponse{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderID:       msg.SenderID,
+		Content:        msg.Content,
+		Image:          msg.Image,
+		ImageURL:       ch.conversationRepo.ImageURL(c.Request.Context(), msg.Image),
+		ReadBy:         []int{},
+		CreatedAt:      msg.CreatedAt,
+	}
+	ch.publishToOthers(c, conversationId, userId, repos.EventMessage, response)
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Message sent",
+		Data:    response,
+	})
+}
+
+// MarkRead godoc
+// @Summary Mark conversation as read
+// @Description Send a read receipt up to message_id, or up to the latest message when omitted
+// @Tags Conversations
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param request body dtos.MarkReadRequest false "Last read message"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.MessageReadEvent}
+// @Failure 404 {object} dtos.Response
+// @Router /conversations/{id}/read [post]
+func (ch *ConversationHandler) MarkRead(c *gin.Context) {
+	userId, conversationId, ok := ch.memberFromCtx(c)
+	if !ok {
+		return
+	}
+
+	var body dtos.MarkReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Invalid body",
+			})
+			return
+		}
+	}
+
+	lastRead, err := ch.conversationRepo.MarkRead(c.Request.Context(), conversationId, userId, body.MessageID)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to mark conversation as read",
+		})
+		return
+	}
+
+	receipt := dtos.MessageReadEvent{
+		ConversationID:    conversationId,
+		UserID:            userId,
+		LastReadMessageID: lastRead,
+	}
+	ch.publishToOthers(c, conversationId, userId, repos.EventMessageRead, receipt)
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Conversation marked as read",
+		Data:    receipt,
+	})
+}
+
+// memberFromCtx reads the conversation id from the path and makes sure the
+// authenticated user takes part in it. It writes the error response itself.
+func (ch *ConversationHandler) memberFromCtx(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return 0, 0, false
+	}
+
+	conversationId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid conversation id",
+		})
+		return 0, 0, false
+	}
+
+	isMember, err := ch.conversationRepo.IsMember(c.Request.Context(), conversationId, userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Internal server error",
+		})
+		return 0, 0, false
+	}
+	if !isMember {
+		// outsiders cannot tell whether the conversation exists
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Conversation not found",
+		})
+		return 0, 0, false
+	}
+
+	return userId, conversationId, true
+}
+
+// canSend stops messages in a 1:1 conversation once either side blocked the
+// other. Group conversations keep working for everyone else. It writes the
+// error response itself.
+func (ch *ConversationHandler) canSend(c *gin.Context, conversationId, userId int) bool {
+	isGroup, err := ch.conversationRepo.IsGroup(c.Request.Context(), conversationId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to send message",
+		})
+		return false
+	}
+	if isGroup {
+		return true
+	}
+
+	members, err := ch.conversationRepo.GetMemberIDs(c.Request.Context(), conversationId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to send message",
+		})
+		return false
+	}
+
+	blocked, err := ch.blockRepo.AnyBlocked(c.Request.Context(), userId, members)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to send message",
+		})
+		return false
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You cannot message this user",
+		})
+		return false
+	}
+
+	return true
+}
+
+func (ch *ConversationHandler) publishToOthers(c *gin.Context, conversationId, senderId int, eventType string, data any) {
+	members, err := ch.conversationRepo.GetMemberIDs(c.Request.Context(), conversationId)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	others := []int{}
+	for _, id := range members {
+		if id != senderId {
+			others = append(others, id)
+		}
+	}
+	ch.eventRepo.PublishToUsers(others, eventType, data)
+}
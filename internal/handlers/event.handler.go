@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	heartbeatInterval = 25 * time.Second
+	// the most posts a stream can receive counters for
+	maxWatchedPosts = 100
+)
+
+type EventHandler struct {
+	eventRepo      *repos.EventRepo
+	authRepo       *repos.AuthRepo
+	suspensionRepo *repos.SuspensionRepo
+}
+
+func NewEventHandler(er *repos.EventRepo, ar *repos.AuthRepo, sr *repos.SuspensionRepo) *EventHandler {
+	return &EventHandler{
+		eventRepo:      er,
+		authRepo:       ar,
+		suspensionRepo: sr,
+	}
+}
+
+// Stream godoc
+// @Summary Realtime events
+// @Description Server-Sent Events stream of new notifications, new posts from followed users and live like/comment counts. Send Last-Event-ID (or ?last_event_id=) to resume after a disconnect. Counters are only sent for the posts passed as ?posts=1,2,3 (up to 100) that the user may see. The stream is closed once the token expires or is logged out, or the account is suspended.
+// @Tags Events
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "Last received event id"
+// @Param last_event_id query string false "Last received event id"
+// @Param posts query string false "Comma separated post ids to receive counters for, up to 100"
+// @Security BearerAuth
+// @Success 200 {string} string "event stream"
+// @Failure 401 {object} dtos.Response
+// @Router /events [get]
+func (eh *EventHandler) Stream(c *gin.Context) {
+	token, claims, err := utils.GetTokenFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("last_event_id")
+	}
+
+	userId := claims.UserId
+
+	requested := []int{}
+	for _, s := range strings.Split(c.Query("posts"), ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && len(requested) < maxWatchedPosts {
+			requested = append(requested, id)
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	watched, err := eh.watchedPosts(ctx, userId, requested)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to open event stream",
+		})
+		return
+	}
+
+	// subscribe before replaying so nothing published in between is lost
+	pubsub, err := eh.eventRepo.Subscribe(ctx, userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to open event stream",
+		})
+		return
+	}
+	defer pubsub.Close()
+
+	var missed []dtos.Event
+	if lastEventId != "" {
+		missed, err = eh.eventRepo.Replay(ctx, userId, lastEventId)
+		if err != nil {
+			// an unknown or malformed id just means there is nothing to resume
+			log.Println("Failed to replay events.\nCause:", err.Error())
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	for _, e := range missed {
+		writeEvent(c.Writer, e)
+		lastEventId = e.ID
+	}
+	c.Writer.Flush()
+
+	messages := pubsub.Channel()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-expired:
+			return false
+		case <-heartbeat.C:
+			// the stream outlives the request that authenticated it, so the
+			// session and what it may see are checked again
+			if !eh.sessionActive(ctx, token, userId) {
+				return false
+			}
+			if len(requested) > 0 {
+				if visible, err := eh.watchedPosts(ctx, userId, requested); err != nil {
+					log.Println("Failed to check watched posts.\nCause:", err.Error())
+				} else {
+					watched = visible
+				}
+			}
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		case msg, ok := <-messages:
+			if !ok {
+				return false
+			}
+
+			var e dtos.Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				log.Println("Invalid event payload.\nCause:", err.Error())
+				return true
+			}
+
+			if e.Type == repos.EventPostStats {
+				var stats dtos.PostStatsEvent
+				if err := json.Unmarshal(e.Data, &stats); err != nil {
+					return true
+				}
+				if !watched[stats.PostID] {
+					return true
+				}
+			} else if e.ID != "" {
+				// already delivered during the replay
+				if !streamIDAfter(e.ID, lastEventId) {
+					return true
+				}
+				lastEventId = e.ID
+			}
+
+			writeEvent(w, e)
+			return true
+		}
+	})
+}
+
+// watchedPosts returns the posts among postIds whose counters the user may
+// receive.
+func (eh *EventHandler) watchedPosts(c context.Context, userId int, postIds []int) (map[int]bool, error) {
+	visible, err := eh.eventRepo.VisiblePosts(c, userId, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	watched := map[int]bool{}
+	for _, id := range visible {
+		watched[id] = true
+	}
+	return watched, nil
+}
+
+// sessionActive tells whether the token that opened a stream is still good:
+// not logged out and its user not suspended. Errors end the stream too, the
+// client reconnects and is authenticated again.
+func (eh *EventHandler) sessionActive(c context.Context, token string, userId int) bool {
+	loggedOut, err := eh.authRepo.IsLoggedOut(c, token)
+	if err != nil {
+		log.Println("Error when checking blacklist redis cache:", err)
+		return false
+	}
+	if loggedOut {
+		return false
+	}
+
+	suspended, err := eh.suspensionRepo.IsSuspended(c, userId)
+	if err != nil {
+		log.Println("Error when checking suspension:", err)
+		return false
+	}
+	return !suspended
+}
+
+func writeEvent(w io.Writer, e dtos.Event) {
+	if e.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", e.ID)
+	}
+	fmt.Fprintf(w, "event: %s\n", e.Type)
+	fmt.Fprintf(w, "data: %s\n\n", e.Data)
+}
+
+// streamIDAfter reports whether Redis stream id a comes after b. Ids have the
+// form "<milliseconds>-<sequence>".
+func streamIDAfter(a, b string) bool {
+	if b == "" {
+		return true
+	}
+
+	aMs, aSeq := splitStreamID(a)
+	bMs, bSeq := splitStreamID(b)
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
+
+func splitStreamID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
@@ -1,33 +1,50 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
 	"github.com/Darari17/social-media/internal/repos"
 	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 type FollowHandler struct {
-	followRepo *repos.FollowRepo
+	followRepo       *repos.FollowRepo
+	notificationRepo *repos.NotificationRepo
+	blockRepo        *repos.BlockRepo
+	velocityRepo     *repos.VelocityRepo
 }
 
-func NewFollowHandler(repo *repos.FollowRepo) *FollowHandler {
-	return &FollowHandler{followRepo: repo}
+func NewFollowHandler(repo *repos.FollowRepo, nr *repos.NotificationRepo, br *repos.BlockRepo, vr *repos.VelocityRepo) *FollowHandler {
+	return &FollowHandler{
+		followRepo:       repo,
+		notificationRepo: nr,
+		blockRepo:        br,
+		velocityRepo:     vr,
+	}
 }
 
 // FollowUser godoc
 // @Summary Follow user
-// @Description Follow another user by ID
+// @Description Follow another user by ID. Following a private account sends a follow request instead. Following too fast is throttled, and following the same user over and over ends the session.
 // @Tags Follow
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID to follow"
-// @Success 200 {object} dtos.Response
+// @Success 200 {object} dtos.Response{data=dtos.FollowResponse}
+// @Success 202 {object} dtos.Response{data=dtos.FollowRequestResponse}
 // @Failure 400 {object} dtos.Response
 // @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /follow/{id} [post]
 func (fh *FollowHandler) FollowUser(c *gin.Context) {
 	followerId, err := utils.GetUserFromCtx(c)
@@ -60,6 +77,103 @@ func (fh *FollowHandler) FollowUser(c *gin.Context) {
 		return
 	}
 
+	blocked, err := fh.blockRepo.IsBlocked(c.Request.Context(), followerId, followingId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to follow user",
+		})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "You cannot follow this user",
+		})
+		return
+	}
+
+	private, err := fh.followRepo.IsPrivate(c.Request.Context(), followingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "User not found",
+		})
+		return
+	}
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to follow user",
+		})
+		return
+	}
+
+	following, err := fh.followRepo.IsFollowing(c.Request.Context(), followerId, followingId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to follow user",
+		})
+		return
+	}
+	if following {
+		c.JSON(http.StatusConflict, dtos.Response{
+			Code:    http.StatusConflict,
+			Success: false,
+			Message: "You already follow this user",
+		})
+		return
+	}
+
+	if !checkVelocity(c, fh.velocityRepo, followerId, models.SignalFollowRate, "") ||
+		!checkVelocity(c, fh.velocityRepo, followerId, models.SignalFollowChurn, strconv.Itoa(followingId)) {
+		return
+	}
+
+	if private {
+		req, err := fh.followRepo.CreateFollowRequest(c.Request.Context(), followerId, followingId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "Follow request already sent",
+			})
+			return
+		}
+		if err != nil {
+			log.Println(err.Error())
+			c.JSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Failed to send follow request",
+			})
+			return
+		}
+
+		notify(c.Request.Context(), fh.notificationRepo, models.Notification{
+			UserID:  followingId,
+			ActorID: followerId,
+			Type:    models.NotificationFollowRequest,
+		})
+
+		c.JSON(http.StatusAccepted, dtos.Response{
+			Code:    http.StatusAccepted,
+			Success: true,
+			Message: "Follow request sent",
+			Data:    req,
+		})
+		return
+	}
+
 	res, err := fh.followRepo.FollowUser(c.Request.Context(), followerId, followingId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
@@ -70,6 +184,12 @@ func (fh *FollowHandler) FollowUser(c *gin.Context) {
 		return
 	}
 
+	notify(c.Request.Context(), fh.notificationRepo, models.Notification{
+		UserID:  followingId,
+		ActorID: followerId,
+		Type:    models.NotificationFollow,
+	})
+
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
 		Success: true,
@@ -80,7 +200,7 @@ func (fh *FollowHandler) FollowUser(c *gin.Context) {
 
 // UnfollowUser godoc
 // @Summary Unfollow user
-// @Description Unfollow another user by ID
+// @Description Unfollow another user by ID, or withdraw a pending follow request
 // @Tags Follow
 // @Produce json
 // @Security BearerAuth
@@ -121,14 +241,35 @@ func (fh *FollowHandler) UnfollowUser(c *gin.Context) {
 		return
 	}
 	if rows == 0 {
-		c.JSON(http.StatusNotFound, dtos.Response{
-			Code:    http.StatusNotFound,
-			Success: false,
-			Message: "Follow relation not found",
+		cancelled, err := fh.followRepo.CancelFollowRequest(c.Request.Context(), followerId, followingId)
+		if err != nil {
+			log.Println(err.Error())
+		}
+		if cancelled == 0 {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Follow relation not found",
+			})
+			return
+		}
+
+		if err := fh.notificationRepo.DeleteNotification(c.Request.Context(), followingId, followerId, models.NotificationFollowRequest, nil); err != nil {
+			log.Println(err.Error())
+		}
+
+		c.JSON(http.StatusOK, dtos.Response{
+			Code:    http.StatusOK,
+			Success: true,
+			Message: "Follow request cancelled",
 		})
 		return
 	}
 
+	if err := fh.notificationRepo.DeleteNotification(c.Request.Context(), followingId, followerId, models.NotificationFollow, nil); err != nil {
+		log.Println(err.Error())
+	}
+
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
 		Success: true,
@@ -142,10 +283,15 @@ func (fh *FollowHandler) UnfollowUser(c *gin.Context) {
 // @Tags Follow
 // @Produce json
 // @Param id path int true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=[]models.User}
 // @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /users/{id}/followers [get]
 func (fh *FollowHandler) GetFollowers(c *gin.Context) {
+	viewerId, _ := utils.GetUserFromCtx(c)
+
 	userIdStr := c.Param("id")
 	userId, err := strconv.Atoi(userIdStr)
 	if err != nil {
@@ -157,7 +303,11 @@ func (fh *FollowHandler) GetFollowers(c *gin.Context) {
 		return
 	}
 
-	users, err := fh.followRepo.GetFollowers(c.Request.Context(), userId)
+	if !fh.canViewAccount(c, userId, viewerId) {
+		return
+	}
+
+	users, err := fh.followRepo.GetFollowers(c.Request.Context(), userId, viewerId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
@@ -181,10 +331,15 @@ func (fh *FollowHandler) GetFollowers(c *gin.Context) {
 // @Tags Follow
 // @Produce json
 // @Param id path int true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=[]models.User}
 // @Failure 400 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /users/{id}/following [get]
 func (fh *FollowHandler) GetFollowing(c *gin.Context) {
+	viewerId, _ := utils.GetUserFromCtx(c)
+
 	userIdStr := c.Param("id")
 	userId, err := strconv.Atoi(userIdStr)
 	if err != nil {
@@ -196,7 +351,11 @@ func (fh *FollowHandler) GetFollowing(c *gin.Context) {
 		return
 	}
 
-	users, err := fh.followRepo.GetFollowing(c.Request.Context(), userId)
+	if !fh.canViewAccount(c, userId, viewerId) {
+		return
+	}
+
+	users, err := fh.followRepo.GetFollowing(c.Request.Context(), userId, viewerId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
@@ -213,3 +372,197 @@ func (fh *FollowHandler) GetFollowing(c *gin.Context) {
 		Data:    users,
 	})
 }
+
+// GetFollowRequests godoc
+// @Summary Get follow requests
+// @Description Get pending requests to follow the authenticated user
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.FollowRequestResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /follow/requests [get]
+func (fh *FollowHandler) GetFollowRequests(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	requests, err := fh.followRepo.GetFollowRequests(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch follow requests",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get follow requests successfully",
+		Data:    requests,
+	})
+}
+
+// AcceptFollowRequest godoc
+// @Summary Accept follow request
+// @Description Accept a pending follow request; the requester becomes a follower
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Follow request ID"
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /follow/requests/{id}/accept [post]
+func (fh *FollowHandler) AcceptFollowRequest(c *gin.Context) {
+	userId, requestId, ok := requestFromCtx(c)
+	if !ok {
+		return
+	}
+
+	requesterId, err := fh.followRepo.AcceptFollowRequest(c.Request.Context(), requestId, userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Follow request not found",
+		})
+		return
+	}
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to accept follow request",
+		})
+		return
+	}
+
+	if err := fh.notificationRepo.DeleteNotification(c.Request.Context(), userId, requesterId, models.NotificationFollowRequest, nil); err != nil {
+		log.Println(err.Error())
+	}
+	notify(c.Request.Context(), fh.notificationRepo, models.Notification{
+		UserID:  requesterId,
+		ActorID: userId,
+		Type:    models.NotificationFollowAccept,
+	})
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Follow request accepted",
+	})
+}
+
+// RejectFollowRequest godoc
+// @Summary Reject follow request
+// @Description Reject a pending follow request. The requester is not told.
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Follow request ID"
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /follow/requests/{id}/reject [post]
+func (fh *FollowHandler) RejectFollowRequest(c *gin.Context) {
+	userId, requestId, ok := requestFromCtx(c)
+	if !ok {
+		return
+	}
+
+	requesterId, err := fh.followRepo.RejectFollowRequest(c.Request.Context(), requestId, userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Follow request not found",
+		})
+		return
+	}
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to reject follow request",
+		})
+		return
+	}
+
+	if err := fh.notificationRepo.DeleteNotification(c.Request.Context(), userId, requesterId, models.NotificationFollowRequest, nil); err != nil {
+		log.Println(err.Error())
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Follow request rejected",
+	})
+}
+
+// canViewAccount stops non-followers from listing the connections of a
+// private account. It writes the error response itself.
+func (fh *FollowHandler) canViewAccount(c *gin.Context, ownerId, viewerId int) bool {
+	visible, err := fh.followRepo.CanViewAccount(c.Request.Context(), ownerId, viewerId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "User not found",
+		})
+		return false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Internal server error",
+		})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "This account is private",
+		})
+		return false
+	}
+	return true
+}
+
+func requestFromCtx(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return 0, 0, false
+	}
+
+	requestId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid follow request id",
+		})
+		return 0, 0, false
+	}
+
+	return userId, requestId, true
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,26 +13,35 @@ import (
 )
 
 type LikeHandler struct {
-	likeRepo *repos.LikeRepo
-	postRepo *repos.PostRepo
+	likeRepo         *repos.LikeRepo
+	postRepo         *repos.PostRepo
+	notificationRepo *repos.NotificationRepo
+	eventRepo        *repos.EventRepo
+	velocityRepo     *repos.VelocityRepo
 }
 
-func NewLikeHandler(r *repos.LikeRepo, p *repos.PostRepo) *LikeHandler {
+func NewLikeHandler(r *repos.LikeRepo, p *repos.PostRepo, n *repos.NotificationRepo, e *repos.EventRepo, v *repos.VelocityRepo) *LikeHandler {
 	return &LikeHandler{
-		likeRepo: r,
-		postRepo: p,
+		likeRepo:         r,
+		postRepo:         p,
+		notificationRepo: n,
+		eventRepo:        e,
+		velocityRepo:     v,
 	}
 }
 
 // LikePost godoc
 // @Summary Like post
-// @Description Like a post by ID
+// @Description Like a post by ID. Liking too fast is throttled.
 // @Tags Likes
 // @Produce json
 // @Param id path int true "Post ID"
 // @Security BearerAuth
 // @Success 201 {object} dtos.Response
 // @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /posts/{id}/like [post]
 func (h *LikeHandler) LikePost(c *gin.Context) {
 	userId, err := utils.GetUserFromCtx(c)
@@ -55,6 +65,20 @@ func (h *LikeHandler) LikePost(c *gin.Context) {
 		return
 	}
 
+	post, err := h.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	if !checkVelocity(c, h.velocityRepo, userId, models.SignalLikeRate, "") {
+		return
+	}
+
 	like := models.Like{
 		UserID: userId,
 		PostID: postId,
@@ -69,6 +93,14 @@ func (h *LikeHandler) LikePost(c *gin.Context) {
 		return
 	}
 
+	notify(c.Request.Context(), h.notificationRepo, models.Notification{
+		UserID:  post.UserID,
+		ActorID: userId,
+		Type:    models.NotificationLike,
+		PostID:  &postId,
+	})
+	h.eventRepo.PublishPostStats(postId)
+
 	c.JSON(http.StatusCreated, dtos.Response{
 		Code:    http.StatusCreated,
 		Success: true,
@@ -117,6 +149,13 @@ func (h *LikeHandler) UnlikePost(c *gin.Context) {
 		return
 	}
 
+	if post, err := h.postRepo.GetPostByID(c.Request.Context(), postId, userId); err == nil {
+		if err := h.notificationRepo.DeleteNotification(c.Request.Context(), post.UserID, userId, models.NotificationLike, &postId); err != nil {
+			log.Println(err.Error())
+		}
+	}
+	h.eventRepo.PublishPostStats(postId)
+
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
 		Success: true,
@@ -133,8 +172,11 @@ func (h *LikeHandler) UnlikePost(c *gin.Context) {
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=[]models.User}
 // @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
 // @Router /posts/{id}/likes [get]
 func (h *LikeHandler) GetLikes(c *gin.Context) {
+	viewerId, _ := utils.GetUserFromCtx(c)
+
 	postIdStr := c.Param("id")
 	postId, err := strconv.Atoi(postIdStr)
 	if err != nil {
@@ -146,7 +188,17 @@ func (h *LikeHandler) GetLikes(c *gin.Context) {
 		return
 	}
 
-	users, err := h.likeRepo.GetLikesByPost(c.Request.Context(), postId)
+	// the post itself may be hidden from the viewer
+	if _, err := h.postRepo.GetPostByID(c.Request.Context(), postId, viewerId); err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	users, err := h.likeRepo.GetLikesByPost(c.Request.Context(), postId, viewerId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type MediaHandler struct {
+	uploadRepo *repos.UploadRepo
+	mediaRepo  *repos.MediaRepo
+	store      storage.Storage
+	policies   utils.UploadPolicies
+}
+
+func NewMediaHandler(ur *repos.UploadRepo, mr *repos.MediaRepo, store storage.Storage, policies utils.UploadPolicies) *MediaHandler {
+	return &MediaHandler{
+		uploadRepo: ur,
+		mediaRepo:  mr,
+		store:      store,
+		policies:   policies,
+	}
+}
+
+// UploadMedia godoc
+// @Summary Upload media
+// @Description Upload an image for a later post in one request. The returned id goes into media_ids of POST /posts before the upload expires.
+// @Tags Media
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Image within the post image policy (see GET /users/profile/storage), stored as thumbnail, medium and full sizes"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.MediaUploadResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 413 {object} dtos.Response
+// @Failure 415 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /media [post]
+func (mh *MediaHandler) UploadMedia(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "File is required",
+		})
+		return
+	}
+
+	if !checkQuota(c, mh.mediaRepo, mh.policies, userId, file.Size) {
+		return
+	}
+
+	variants, err := utils.FileUpload(c, mh.store, mh.mediaRepo, file, mh.policies.Policy(models.MediaPost))
+	if err != nil {
+		uploadFailed(c, err)
+		return
+	}
+
+	filename := variants[models.ImageFull].Filename
+	upload := models.MediaUpload{
+		UserID:    userId,
+		Status:    models.UploadPending,
+		SizeBytes: file.Size,
+		Filename:  &filename,
+		Variants:  variants,
+		Received:  file.Size,
+	}
+	if err := mh.uploadRepo.CreateUpload(c.Request.Context(), &upload); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to upload media",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Media uploaded",
+		Data:    mh.uploadResponse(&upload),
+	})
+}
+
+// StartUpload godoc
+// @Summary Start resumable upload
+// @Description Start uploading a large image in chunks. Send the chunks in order with PATCH /media/{id}; HEAD /media/{id} tells where to resume after a failure.
+// @Tags Media
+// @Accept json
+// @Produce json
+// @Param request body dtos.MediaUploadRequest true "Image size in bytes, within the post image policy"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.MediaUploadResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 413 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /media/uploads [post]
+func (mh *MediaHandler) StartUpload(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var body dtos.MediaUploadRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if err := mh.policies.Policy(models.MediaPost).CheckSize(body.Size); err != nil {
+		uploadFailed(c, err)
+		return
+	}
+	if !checkQuota(c, mh.mediaRepo, mh.policies, userId, body.Size) {
+		return
+	}
+
+	upload := models.MediaUpload{
+		UserID:    userId,
+		Status:    models.UploadUploading,
+		SizeBytes: body.Size,
+	}
+	if err := mh.uploadRepo.CreateUpload(c.Request.Context(), &upload); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to start upload",
+		})
+		return
+	}
+
+	c.Header("Upload-Offset", "0")
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Upload started",
+		Data:    mh.uploadResponse(&upload),
+	})
+}
+
+// GetUploadOffset godoc
+// @Summary Get upload offset
+// @Description Tell how many bytes of a resumable upload were received, in the Upload-Offset header, and its size, in Upload-Length.
+// @Tags Media
+// @Param id path int true "Media ID"
+// @Security BearerAuth
+// @Success 200 "Offset in the headers"
+// @Header 200 {integer} Upload-Offset "Bytes received"
+// @Header 200 {integer} Upload-Length "Size of the image"
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /media/{id} [head]
+func (mh *MediaHandler) GetUploadOffset(c *gin.Context) {
+	upload, ok := mh.ownUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.SizeBytes, 10))
+	c.Status(http.StatusOK)
+}
+
+// UploadChunk godoc
+// @Summary Upload chunk
+// @Description Send the next chunk of a resumable upload as the raw request body. Upload-Offset must be the number of bytes received so far. Once the last chunk is in, the image is processed and the upload can go into a post.
+// @Tags Media
+// @Accept application/offset+octet-stream
+// @Produce json
+// @Param id path int true "Media ID"
+// @Param Upload-Offset header int true "Offset of the chunk"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.MediaUploadResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Failure 413 {object} dtos.Response
+// @Failure 415 {object} dtos.Response
+// @Router /media/{id} [patch]
+func (mh *MediaHandler) UploadChunk(c *gin.Context) {
+	upload, ok := mh.ownUpload(c)
+	if !ok {
+		return
+	}
+
+	if upload.Status != models.UploadUploading {
+		c.JSON(http.StatusConflict, dtos.Response{
+			Code:    http.StatusConflict,
+			Success: false,
+			Message: "Upload is not in progress",
+			Data:    mh.uploadResponse(upload),
+		})
+		return
+	}
+
+	// every chunk came in but storing the image failed last time
+	if upload.Received == upload.SizeBytes {
+		if !mh.completeUpload(c, upload) {
+			return
+		}
+		c.JSON(http.StatusOK, dtos.Response{
+			Code:    http.StatusOK,
+			Success: true,
+			Message: "Media uploaded",
+			Data:    mh.uploadResponse(upload),
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid Upload-Offset",
+		})
+		return
+	}
+
+	// anything past the announced size is refused rather than cut off
+	remaining := upload.SizeBytes - offset
+	chunk, err := io.ReadAll(io.LimitReader(c.Request.Body, max(remaining, 0)+1))
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Failed to read chunk",
+		})
+		return
+	}
+	if int64(len(chunk)) > remaining {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Chunk goes past the upload size",
+		})
+		return
+	}
+	if len(chunk) == 0 {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Chunk is empty",
+		})
+		return
+	}
+
+	received, ok, err := mh.uploadRepo.AppendChunk(c.Request.Context(), upload.ID, offset, chunk)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to save chunk",
+		})
+		return
+	}
+	upload.Received = received
+	c.Header("Upload-Offset", strconv.FormatInt(received, 10))
+	if !ok {
+		c.JSON(http.StatusConflict, dtos.Response{
+			Code:    http.StatusConflict,
+			Success: false,
+			Message: fmt.Sprintf("Upload-Offset must be %d", received),
+			Data:    mh.uploadResponse(upload),
+		})
+		return
+	}
+
+	if received < upload.SizeBytes {
+		c.JSON(http.StatusOK, dtos.Response{
+			Code:    http.StatusOK,
+			Success: true,
+			Message: "Chunk received",
+			Data:    mh.uploadResponse(upload),
+		})
+		return
+	}
+
+	if !mh.completeUpload(c, upload) {
+		return
+	}
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Media uploaded",
+		Data:    mh.uploadResponse(upload),
+	})
+}
+
+// completeUpload processes and stores the image of an upload whose chunks all
+// came in. It writes the error response and returns false when that fails.
+func (mh *MediaHandler) completeUpload(c *gin.Context, upload *models.MediaUpload) bool {
+	data, err := mh.uploadRepo.GetChunks(c.Request.Context(), upload.ID)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to upload media",
+		})
+		return false
+	}
+
+	// the upload already counts in the usage, but uploads started side by
+	// side may have taken more than what was left of the quota
+	if !checkQuota(c, mh.mediaRepo, mh.policies, upload.UserID, 0) {
+		if err := mh.uploadRepo.FailUpload(c.Request.Context(), upload.ID); err != nil {
+			log.Println(err.Error())
+		}
+		return false
+	}
+
+	variants, err := utils.StoreImage(c, mh.store, mh.mediaRepo, data, mh.policies.Policy(models.MediaPost))
+	if err != nil {
+		if err := mh.uploadRepo.FailUpload(c.Request.Context(), upload.ID); err != nil {
+			log.Println(err.Error())
+		}
+		uploadFailed(c, err)
+		return false
+	}
+
+	filename := variants[models.ImageFull].Filename
+	upload.Filename = &filename
+	upload.Variants = variants
+	if err := mh.uploadRepo.CompleteUpload(c.Request.Context(), upload); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to upload media",
+		})
+		return false
+	}
+	return true
+}
+
+// ownUpload loads the upload named in the path if it belongs to the caller.
+// It writes the error response and returns false otherwise.
+func (mh *MediaHandler) ownUpload(c *gin.Context) (*models.MediaUpload, bool) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid media id",
+		})
+		return nil, false
+	}
+
+	upload, err := mh.uploadRepo.GetUpload(c.Request.Context(), id, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Upload not found or expired",
+			})
+			return nil, false
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get upload",
+		})
+		return nil, false
+	}
+	return upload, true
+}
+
+func (mh *MediaHandler) uploadResponse(upload *models.MediaUpload) dtos.MediaUploadResponse {
+	utils.SetImageURLs(mh.store, upload.Variants)
+	return dtos.MediaUploadResponse{
+		ID:        upload.ID,
+		Status:    upload.Status,
+		Size:      upload.SizeBytes,
+		Received:  upload.Received,
+		Filename:  upload.Filename,
+		Variants:  upload.Variants,
+		ExpiresAt: upload.ExpiresAt,
+		CreatedAt: upload.CreatedAt,
+	}
+}
+
+// uploadFailed answers an upload that could not be stored: 413 when it is too
+// big, 415 when its type is not allowed and 400 for anything else wrong with
+// it.
+func uploadFailed(c *gin.Context, err error) {
+	log.Println(err.Error())
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, utils.ErrFileTooLarge), errors.Is(err, utils.ErrQuotaFull):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, utils.ErrFileType):
+		status = http.StatusUnsupportedMediaType
+	}
+	c.JSON(status, dtos.Response{
+		Code:    status,
+		Success: false,
+		Message: err.Error(),
+	})
+}
+
+// checkQuota refuses an upload of size bytes that does not fit in what is
+// left of the user's storage quota. It writes the error response and returns
+// false then.
+func checkQuota(c *gin.Context, mediaRepo *repos.MediaRepo, policies utils.UploadPolicies, userId int, size int64) bool {
+	usage, err := mediaRepo.GetStorageUsage(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to check storage quota",
+		})
+		return false
+	}
+
+	quota := storageQuota(policies, usage)
+	if usage.Total+size > quota {
+		uploadFailed(c, fmt.Errorf("%w (%s of %s used)", utils.ErrQuotaFull, utils.FormatBytes(usage.Total), utils.FormatBytes(quota)))
+		return false
+	}
+	return true
+}
+
+// storageQuota is the user's own quota or else the default one.
+func storageQuota(policies utils.UploadPolicies, usage *models.StorageUsage) int64 {
+	if usage.Quota != nil {
+		return *usage.Quota
+	}
+	return policies.DefaultQuota
+}
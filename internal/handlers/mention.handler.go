@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type MentionHandler struct {
+	mentionRepo *repos.MentionRepo
+}
+
+func NewMentionHandler(mr *repos.MentionRepo) *MentionHandler {
+	return &MentionHandler{mentionRepo: mr}
+}
+
+// GetMentions godoc
+// @Summary Get mentions
+// @Description Get posts and comments where the authenticated user was mentioned
+// @Tags Users
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.MentionFeedResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /users/profile/mentions [get]
+func (mh *MentionHandler) GetMentions(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	limit, offset := utils.GetPagination(c)
+	mentions, err := mh.mentionRepo.GetMentionsOfUser(c.Request.Context(), userId, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch mentions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get mentions successfully",
+		Data:    mentions,
+	})
+}
+
+// notifyMentions tells every newly mentioned user about the post or comment.
+func notifyMentions(c context.Context, nr *repos.NotificationRepo, actorId, postId int, commentId *int, userIds []int) {
+	for _, id := range userIds {
+		notify(c, nr, models.Notification{
+			UserID:    id,
+			ActorID:   actorId,
+			Type:      models.NotificationMention,
+			PostID:    &postId,
+			CommentID: commentId,
+		})
+	}
+}
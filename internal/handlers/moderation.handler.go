@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type ModerationHandler struct {
+	reportRepo       *repos.ReportRepo
+	notificationRepo *repos.NotificationRepo
+	suspensionRepo   *repos.SuspensionRepo
+}
+
+func NewModerationHandler(rr *repos.ReportRepo, nr *repos.NotificationRepo, sr *repos.SuspensionRepo) *ModerationHandler {
+	return &ModerationHandler{
+		reportRepo:       rr,
+		notificationRepo: nr,
+		suspensionRepo:   sr,
+	}
+}
+
+// GetReports godoc
+// @Summary Get moderation queue
+// @Description Get reports oldest first, each with the number of open reports about the same target. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param status query string false "open, actioned or dismissed"
+// @Param target_type query string false "post, comment or user"
+// @Param reason query string false "Report reason"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.ModerationQueueItem}
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Router /admin/reports [get]
+func (mh *ModerationHandler) GetReports(c *gin.Context) {
+	var filter dtos.ReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	limit, offset := utils.GetPagination(c)
+	reports, err := mh.reportRepo.GetReports(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch reports",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get reports successfully",
+		Data:    reports,
+	})
+}
+
+// ResolveReport godoc
+// @Summary Resolve report
+// @Description Mark an open report as actioned or dismissed, optionally taking an action (hide, restore, warn, suspend) on its target. Other open reports about the same target are resolved with it and every reporter is notified. Moderators only. Suspensions last duration_hours, or forever when it is empty.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Report ID"
+// @Param body body dtos.ResolveReportRequest true "Resolution"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /admin/reports/{id} [patch]
+func (mh *ModerationHandler) ResolveReport(c *gin.Context) {
+	moderatorId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	reportId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid report id",
+		})
+		return
+	}
+
+	var req dtos.ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Action != nil && req.Status == models.ReportDismissed {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "A dismissed report cannot have an action",
+		})
+		return
+	}
+
+	report, err := mh.reportRepo.GetReportByID(c.Request.Context(), reportId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Report not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to resolve report",
+		})
+		return
+	}
+
+	var action *models.ModerationAction
+	if req.Action != nil {
+		if !validModerationAction(c, report.TargetType, *req.Action) {
+			return
+		}
+		action = &models.ModerationAction{
+			Action: *req.Action,
+			Reason: req.Reason,
+		}
+	}
+
+	reporters, err := mh.reportRepo.ResolveReport(c.Request.Context(), reportId, moderatorId, req.Status, action)
+	if err != nil {
+		if errors.Is(err, repos.ErrReportResolved) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "Report already resolved",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to resolve report",
+		})
+		return
+	}
+
+	if action != nil {
+		mh.applyToOwner(c, action, req.DurationHours)
+	}
+	for _, reporterId := range reporters {
+		notify(c.Request.Context(), mh.notificationRepo, models.Notification{
+			UserID:  reporterId,
+			ActorID: moderatorId,
+			Type:    models.NotificationReportResolved,
+		})
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Report resolved successfully",
+	})
+}
+
+// TakeAction godoc
+// @Summary Take moderation action
+// @Description Hide or restore a post or comment, or warn or suspend the user behind a target, without a report. The action is recorded with its reason. Moderators only. Suspensions last duration_hours, or forever when it is empty.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param body body dtos.ModerationActionRequest true "Action (hide, restore, warn, suspend)"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.ModerationActionResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /admin/actions [post]
+func (mh *ModerationHandler) TakeAction(c *gin.Context) {
+	moderatorId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var req dtos.ModerationActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !validModerationAction(c, req.TargetType, req.Action) {
+		return
+	}
+
+	if _, _, err := mh.reportRepo.TargetOwner(c.Request.Context(), req.TargetType, req.TargetID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Target not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to take action",
+		})
+		return
+	}
+
+	action := models.ModerationAction{
+		ModeratorID: moderatorId,
+		Action:      req.Action,
+		TargetType:  req.TargetType,
+		TargetID:    req.TargetID,
+		Reason:      req.Reason,
+	}
+	if err := mh.reportRepo.TakeAction(c.Request.Context(), &action); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to take action",
+		})
+		return
+	}
+
+	mh.applyToOwner(c, &action, req.DurationHours)
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Action taken successfully",
+		Data: dtos.ModerationActionResponse{
+			ID:          action.ID,
+			ModeratorID: action.ModeratorID,
+			Action:      action.Action,
+			TargetType:  action.TargetType,
+			TargetID:    action.TargetID,
+			ReportID:    action.ReportID,
+			Reason:      action.Reason,
+			CreatedAt:   action.CreatedAt,
+		},
+	})
+}
+
+// GetActions godoc
+// @Summary Get moderation log
+// @Description Get the moderator actions, newest first. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param target_type query string false "post, comment or user"
+// @Param target_id query int false "Target ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.ModerationActionResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Router /admin/actions [get]
+func (mh *ModerationHandler) GetActions(c *gin.Context) {
+	targetId, _ := strconv.Atoi(c.Query("target_id"))
+
+	limit, offset := utils.GetPagination(c)
+	actions, err := mh.reportRepo.GetActions(c.Request.Context(), c.Query("target_type"), targetId, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch moderation actions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get moderation actions successfully",
+		Data:    actions,
+	})
+}
+
+// applyToOwner carries out the part of an action aimed at the author of the
+// target: a warning notification or a suspension for the given number of
+// hours. A failure is only logged, the action itself is already recorded.
+func (mh *ModerationHandler) applyToOwner(c *gin.Context, action *models.ModerationAction, hours *int) {
+	if action.Action != models.ModerationWarn && action.Action != models.ModerationSuspend {
+		return
+	}
+
+	ownerId, postId, err := mh.reportRepo.TargetOwner(c.Request.Context(), action.TargetType, action.TargetID)
+	if err != nil {
+		log.Println("Failed to find the author of the target.\nCause:", err.Error())
+		return
+	}
+
+	if action.Action == models.ModerationSuspend {
+		if _, err := mh.suspensionRepo.Suspend(c.Request.Context(), ownerId, hours, action.Reason); err != nil {
+			log.Println("Failed to suspend user.\nCause:", err.Error())
+		}
+		return
+	}
+
+	n := models.Notification{
+		UserID:  ownerId,
+		ActorID: action.ModeratorID,
+		Type:    models.NotificationWarning,
+		PostID:  postId,
+	}
+	if action.TargetType == models.ReportTargetComment {
+		n.CommentID = &action.TargetID
+	}
+	notify(c.Request.Context(), mh.notificationRepo, n)
+}
+
+// validModerationAction makes sure the action exists and fits the target. It
+// writes the error response itself.
+func validModerationAction(c *gin.Context, targetType, action string) bool {
+	switch action {
+	case models.ModerationHide, models.ModerationRestore:
+		if targetType == models.ReportTargetUser {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Only posts and comments can be hidden or restored",
+			})
+			return false
+		}
+		return true
+	case models.ModerationWarn, models.ModerationSuspend:
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, dtos.Response{
+		Code:    http.StatusBadRequest,
+		Success: false,
+		Message: "Invalid moderation action",
+	})
+	return false
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type MutedWordHandler struct {
+	mutedWordRepo *repos.MutedWordRepo
+}
+
+func NewMutedWordHandler(mr *repos.MutedWordRepo) *MutedWordHandler {
+	return &MutedWordHandler{mutedWordRepo: mr}
+}
+
+// GetMutedWords godoc
+// @Summary Get muted words
+// @Description Get the words the authenticated user muted
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.MutedWordResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /users/profile/muted-words [get]
+func (mh *MutedWordHandler) GetMutedWords(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	words, err := mh.mutedWordRepo.GetMutedWords(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch muted words",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get muted words successfully",
+		Data:    words,
+	})
+}
+
+// MuteWord godoc
+// @Summary Mute word
+// @Description Hide posts and mentions containing the word from the authenticated user's feeds. Only whole words match and case is ignored: muting "cat" does not hide "category".
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body dtos.MutedWordRequest true "Word to mute"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.MutedWordResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /users/profile/muted-words [post]
+func (mh *MutedWordHandler) MuteWord(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var req dtos.MutedWordRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Word) == "" {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body",
+		})
+		return
+	}
+
+	word := models.MutedWord{
+		UserID: userId,
+		Word:   req.Word,
+	}
+	if err := mh.mutedWordRepo.MuteWord(c.Request.Context(), &word); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "You have already muted this word",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to mute word",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Muted word successfully",
+		Data: dtos.MutedWordResponse{
+			ID:        word.ID,
+			Word:      word.Word,
+			CreatedAt: word.CreatedAt,
+		},
+	})
+}
+
+// UnmuteWord godoc
+// @Summary Unmute word
+// @Description Stop hiding posts containing a muted word
+// @Tags Users
+// @Produce json
+// @Param id path int true "Muted word ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /users/profile/muted-words/{id} [delete]
+func (mh *MutedWordHandler) UnmuteWord(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	wordId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid muted word id",
+		})
+		return
+	}
+
+	rows, err := mh.mutedWordRepo.UnmuteWord(c.Request.Context(), userId, wordId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to unmute word",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Muted word not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Unmuted word successfully",
+	})
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type NotificationHandler struct {
+	notificationRepo *repos.NotificationRepo
+}
+
+func NewNotificationHandler(nr *repos.NotificationRepo) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: nr}
+}
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get grouped notifications of the authenticated user with the unread count
+// @Tags Notifications
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.NotificationListResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /notifications [get]
+func (nh *NotificationHandler) GetNotifications(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	limit, offset := utils.GetPagination(c)
+	notifications, err := nh.notificationRepo.GetNotifications(c.Request.Context(), userId, limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch notifications",
+		})
+		return
+	}
+
+	unread, err := nh.notificationRepo.CountUnread(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch notifications",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get notifications successfully",
+		Data: dtos.NotificationListResponse{
+			UnreadCount:   unread,
+			Notifications: notifications,
+		},
+	})
+}
+
+// GetUnreadCount godoc
+// @Summary Get unread notification count
+// @Description Get the number of unread notification groups for badges
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.UnreadCountResponse}
+// @Failure 401 {object} dtos.Response
+// @Router /notifications/unread-count [get]
+func (nh *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	unread, err := nh.notificationRepo.CountUnread(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to count notifications",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get unread count successfully",
+		Data:    dtos.UnreadCountResponse{UnreadCount: unread},
+	})
+}
+
+// MarkRead godoc
+// @Summary Mark notification as read
+// @Description Mark a notification, and the group it is shown in, as read
+// @Tags Notifications
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /notifications/{id}/read [patch]
+func (nh *NotificationHandler) MarkRead(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	notificationId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid notification id",
+		})
+		return
+	}
+
+	if err := nh.notificationRepo.MarkRead(c.Request.Context(), userId, notificationId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Notification not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to mark notification as read",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Notification marked as read",
+	})
+}
+
+// MarkAllRead godoc
+// @Summary Mark all notifications as read
+// @Description Mark every notification of the authenticated user as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Router /notifications/read-all [patch]
+func (nh *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := nh.notificationRepo.MarkAllRead(c.Request.Context(), userId); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to mark notifications as read",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "All notifications marked as read",
+	})
+}
+
+// GetPreferences godoc
+// @Summary Get notification preferences
+// @Description Get which notification types are enabled for the authenticated user
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.NotificationPreferences}
+// @Failure 401 {object} dtos.Response
+// @Router /notifications/preferences [get]
+func (nh *NotificationHandler) GetPreferences(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	prefs, err := nh.notificationRepo.GetPreferences(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch preferences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get preferences successfully",
+		Data:    prefs,
+	})
+}
+
+// UpdatePreferences godoc
+// @Summary Update notification preferences
+// @Description Turn notification types on or off, e.g. {"like": false}
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param request body dtos.NotificationPreferences true "Preferences"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.NotificationPreferences}
+// @Failure 400 {object} dtos.Response
+// @Router /notifications/preferences [patch]
+func (nh *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var body dtos.NotificationPreferences
+	if err := c.ShouldBindJSON(&body); err != nil || len(body) == 0 {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body",
+		})
+		return
+	}
+
+	for t := range body {
+		if !isNotificationType(t) {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Unknown notification type: " + t,
+			})
+			return
+		}
+	}
+
+	if err := nh.notificationRepo.UpdatePreferences(c.Request.Context(), userId, body); err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to update preferences",
+		})
+		return
+	}
+
+	prefs, err := nh.notificationRepo.GetPreferences(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Preferences updated",
+		Data:    prefs,
+	})
+}
+
+func isNotificationType(t string) bool {
+	for _, nt := range models.NotificationTypes {
+		if nt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// notify records a notification without failing the request that caused it.
+func notify(c context.Context, nr *repos.NotificationRepo, n models.Notification) {
+	if err := nr.CreateNotification(c, &n); err != nil {
+		log.Println("Failed to create notification.\nCause:", err.Error())
+	}
+}
+
+// notifyFollowers tells the author's followers about a post that was just
+// published, without failing the request that published it.
+func notifyFollowers(c context.Context, nr *repos.NotificationRepo, post dtos.PostResponse) {
+	if err := nr.NotifyFollowers(c, post.UserID, post.ID, post.Visibility); err != nil {
+		log.Println("Failed to notify followers.\nCause:", err.Error())
+	}
+}
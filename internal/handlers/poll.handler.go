@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type PollHandler struct {
+	pollRepo *repos.PollRepo
+	postRepo *repos.PostRepo
+}
+
+func NewPollHandler(pollRepo *repos.PollRepo, postRepo *repos.PostRepo) *PollHandler {
+	return &PollHandler{
+		pollRepo: pollRepo,
+		postRepo: postRepo,
+	}
+}
+
+// Vote godoc
+// @Summary Vote in poll
+// @Description Vote in the poll of a post: one option, or several when the poll allows multiple choices. Each user votes once and the results are shown after voting.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param body body dtos.PollVoteRequest true "Chosen options"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.PollResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /posts/{postId}/poll/votes [post]
+func (h *PollHandler) Vote(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	postId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post ID",
+		})
+		return
+	}
+
+	var req dtos.PollVoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid body",
+		})
+		return
+	}
+
+	post, err := h.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	if err != nil || post.Status != models.PostPublished {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	if err := h.pollRepo.Vote(c.Request.Context(), postId, userId, req.OptionIDs); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "This post has no poll",
+			})
+		case errors.Is(err, repos.ErrPollClosed):
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "This poll is closed",
+			})
+		case errors.Is(err, repos.ErrAlreadyVoted):
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "You have already voted in this poll",
+			})
+		case errors.Is(err, repos.ErrInvalidChoices):
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Choose options of this poll, only one unless it allows multiple choices",
+			})
+		default:
+			log.Println(err.Error())
+			c.JSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Failed to vote",
+			})
+		}
+		return
+	}
+
+	post, _ = h.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	var poll *dtos.PollResponse
+	if post != nil {
+		poll = post.Poll
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Voted successfully",
+		Data:    poll,
+	})
+}
+
+// newPoll turns the poll fields of a new post into its poll, or nil when the
+// post has none. The poll has to stay open for a while after the post is
+// published at publishAt. It writes the error response and returns false when
+// the poll is not valid.
+func newPoll(c *gin.Context, body dtos.PostRequest, publishAt time.Time) (*models.Poll, bool) {
+	if len(body.PollOptions) == 0 {
+		if body.PollExpiresAt != nil || body.PollMultiple {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "A poll needs options",
+			})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	if len(body.PollOptions) < models.MinPollOptions || len(body.PollOptions) > models.MaxPollOptions {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: fmt.Sprintf("A poll has %d to %d options", models.MinPollOptions, models.MaxPollOptions),
+		})
+		return nil, false
+	}
+
+	poll := models.Poll{Multiple: body.PollMultiple}
+	seen := map[string]bool{}
+	for _, text := range body.PollOptions {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > models.MaxPollOptionLength || seen[strings.ToLower(text)] {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: fmt.Sprintf("Poll options must be different and 1 to %d characters long", models.MaxPollOptionLength),
+			})
+			return nil, false
+		}
+		seen[strings.ToLower(text)] = true
+		poll.Options = append(poll.Options, models.PollOption{Text: text})
+	}
+
+	if body.PollExpiresAt == nil || !body.PollExpiresAt.After(publishAt) || body.PollExpiresAt.Sub(publishAt) > models.MaxPollDuration {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: fmt.Sprintf("poll_expires_at must be after the post is published and within %d days", int(models.MaxPollDuration.Hours()/24)),
+		})
+		return nil, false
+	}
+	poll.ExpiresAt = *body.PollExpiresAt
+	return &poll, true
+}
+
+// pollResponse shows a poll that was just created; nobody has voted yet.
+func pollResponse(poll *models.Poll) *dtos.PollResponse {
+	if poll == nil {
+		return nil
+	}
+	response := dtos.PollResponse{
+		ID:        poll.ID,
+		Multiple:  poll.Multiple,
+		ExpiresAt: poll.ExpiresAt,
+		MyChoices: []int{},
+		Options:   make([]dtos.PollOptionResponse, len(poll.Options)),
+	}
+	for i, o := range poll.Options {
+		response.Options[i] = dtos.PollOptionResponse{
+			ID:   o.ID,
+			Text: o.Text,
+		}
+	}
+	return &response
+}
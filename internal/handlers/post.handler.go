@@ -1,37 +1,76 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
 	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 type PostHandler struct {
-	postRepo *repos.PostRepo
+	postRepo         *repos.PostRepo
+	mentionRepo      *repos.MentionRepo
+	notificationRepo *repos.NotificationRepo
+	eventRepo        *repos.EventRepo
+	reportRepo       *repos.ReportRepo
+	screener         utils.Screener
+	store            storage.Storage
+	uploadRepo       *repos.UploadRepo
+	linkRepo         *repos.LinkPreviewRepo
+	policy           utils.PostPolicy
 }
 
-func NewPostHandler(postRepo *repos.PostRepo) *PostHandler {
-	return &PostHandler{postRepo: postRepo}
+func NewPostHandler(postRepo *repos.PostRepo, mentionRepo *repos.MentionRepo, notificationRepo *repos.NotificationRepo, eventRepo *repos.EventRepo, reportRepo *repos.ReportRepo, screener utils.Screener, store storage.Storage, uploadRepo *repos.UploadRepo, linkRepo *repos.LinkPreviewRepo, policy utils.PostPolicy) *PostHandler {
+	return &PostHandler{
+		postRepo:         postRepo,
+		mentionRepo:      mentionRepo,
+		notificationRepo: notificationRepo,
+		eventRepo:        eventRepo,
+		reportRepo:       reportRepo,
+		screener:         screener,
+		store:            store,
+		uploadRepo:       uploadRepo,
+		linkRepo:         linkRepo,
+		policy:           policy,
+	}
 }
 
 // CreatePost godoc
 // @Summary Create post
-// @Description Create a new post
+// @Description Create a new post with a gallery of up to 4 images. The content is screened first: it may be refused, published behind a content warning, or held hidden until a moderator reviews it (202). A draft is saved without being published and a post with publish_at is published at that time; nobody is notified until then. A poll can be attached; its results are hidden from each viewer until they vote or the poll closes. The first link in the content gets a preview (link_preview) once its page is fetched in the background.
 // @Tags Posts
 // @Accept multipart/form-data
 // @Produce json
 // @Param content formData string false "Post content"
-// @Param image formData file false "Post image"
+// @Param media_ids formData []int false "IDs of images uploaded through POST /media, up to 4, in gallery order" collectionFormat(multi)
+// @Param alt_texts formData []string false "Alt text of each image, in the same order" collectionFormat(multi)
+// @Param visibility formData string false "Audience: public (default), followers, close_friends, only_me or unlisted"
+// @Param draft formData bool false "Save as a draft"
+// @Param publish_at formData string false "When to publish the post, RFC 3339 and in the future"
+// @Param poll_options formData []string false "Options of a poll, 2 to 4" collectionFormat(multi)
+// @Param poll_multiple formData bool false "Let voters choose several options"
+// @Param poll_expires_at formData string false "When the poll closes, RFC 3339, within 7 days of publishing"
 // @Security BearerAuth
 // @Success 201 {object} dtos.Response{data=dtos.PostResponse}
+// @Success 202 {object} dtos.Response{data=dtos.PostResponse}
 // @Failure 400 {object} dtos.Response
 // @Failure 401 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
 // @Router /posts [post]
 func (ph *PostHandler) CreatePost(c *gin.Context) {
 	userId, err := utils.GetUserFromCtx(c)
@@ -56,29 +95,83 @@ func (ph *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
-	var imagePath *string
-	if body.Image != nil {
-		if filename, err := utils.FileUpload(c, body.Image, "posts"); err != nil {
-			log.Println(err.Error())
+	if body.Visibility == "" {
+		body.Visibility = models.PostPublic
+	}
+	if !isPostVisibility(body.Visibility) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid visibility",
+		})
+		return
+	}
+
+	status := models.PostPublished
+	if body.Draft {
+		status = models.PostDraft
+	}
+	if body.PublishAt != nil {
+		if body.Draft {
 			c.JSON(http.StatusBadRequest, dtos.Response{
 				Code:    http.StatusBadRequest,
 				Success: false,
-				Message: err.Error(),
+				Message: "A post is either a draft or scheduled",
 			})
 			return
-		} else {
-			imagePath = &filename
 		}
+		if !validPublishAt(c, *body.PublishAt) {
+			return
+		}
+		status = models.PostScheduled
+	}
+
+	publishAt := time.Now()
+	if body.PublishAt != nil {
+		publishAt = *body.PublishAt
+	}
+	poll, ok := newPoll(c, body, publishAt)
+	if !ok {
+		return
+	}
+
+	screened, ok := screenContent(c, ph.screener, body.Content)
+	if !ok {
+		return
+	}
+
+	if len(body.MediaIDs) > models.MaxPostMedia {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: fmt.Sprintf("A post can have at most %d images", models.MaxPostMedia),
+		})
+		return
+	}
+
+	media, ok := pendingPostMedia(c, ph.uploadRepo, userId, body.MediaIDs, body.AltTexts)
+	if !ok {
+		return
 	}
 
 	post := models.Post{
-		UserID:  userId,
-		Content: &body.Content,
-		Image:   imagePath,
+		UserID:     userId,
+		Content:    &body.Content,
+		Media:      media,
+		Visibility: body.Visibility,
+		Status:     status,
+		PublishAt:  body.PublishAt,
+		Poll:       poll,
+		LinkURL:    firstLink(body.Content),
 	}
+	post.ContentWarning, post.HiddenAt = screenedFields(screened)
 
 	if err := ph.postRepo.CreatePost(c.Request.Context(), &post); err != nil {
 		log.Println(err.Error())
+		if errors.Is(err, repos.ErrUploadUnavailable) {
+			mediaUnavailable(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
 			Success: false,
@@ -87,31 +180,156 @@ func (ph *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	held := post.HiddenAt != nil
+
+	// the preview shows up on the post once it is fetched
+	if post.LinkURL != nil {
+		ph.linkRepo.UnfurlInBackground(*post.LinkURL)
+	}
+
+	mentions := []dtos.MentionResponse{}
+	if body.Content != "" {
+		saved, mentioned, err := ph.mentionRepo.SaveMentions(c.Request.Context(), post.ID, nil, userId, body.Content)
+		if err != nil {
+			log.Println("Failed to save mentions.\nCause:", err.Error())
+		} else {
+			mentions = saved
+			// nobody is told about a held post until a moderator lets it
+			// through, nor about a draft or scheduled post until it is
+			// published
+			if !held && status == models.PostPublished {
+				notifyMentions(c.Request.Context(), ph.notificationRepo, userId, post.ID, nil, mentioned)
+			}
+		}
+	}
+
+	response := dtos.PostResponse{
+		ID:             post.ID,
+		UserID:         post.UserID,
+		Content:        post.Content,
+		Media:          postMediaResponses(ph.store, post.Media),
+		Visibility:     post.Visibility,
+		ContentWarning: post.ContentWarning,
+		Mentions:       mentions,
+		Poll:           pollResponse(post.Poll),
+		Status:         post.Status,
+		PublishAt:      post.PublishAt,
+		CreatedAt:      post.CreatedAt,
+		UpdatedAt:      post.UpdatedAt,
+		DeletedAt:      post.DeletedAt,
+	}
+	if len(response.Media) > 0 {
+		response.Image = &response.Media[0].Filename
+		response.ImageVariants = response.Media[0].Variants
+	}
+
+	if held {
+		holdForReview(c, ph.reportRepo, models.ReportTargetPost, post.ID, screened)
+		c.JSON(http.StatusAccepted, dtos.Response{
+			Code:    http.StatusAccepted,
+			Success: true,
+			Message: "Post is held for review",
+			Data:    response,
+		})
+		return
+	}
+
+	if status != models.PostPublished {
+		message := "Draft saved successfully"
+		if status == models.PostScheduled {
+			message = "Post scheduled successfully"
+		}
+		c.JSON(http.StatusCreated, dtos.Response{
+			Code:    http.StatusCreated,
+			Success: true,
+			Message: message,
+			Data:    response,
+		})
+		return
+	}
+	notifyFollowers(c.Request.Context(), ph.notificationRepo, response)
+	ph.eventRepo.PublishNewPost(response)
+
 	c.JSON(http.StatusCreated, dtos.Response{
 		Code:    http.StatusCreated,
 		Success: true,
 		Message: "Post created successfully",
-		Data: dtos.PostResponse{
-			ID:        post.ID,
-			UserID:    post.UserID,
-			Content:   post.Content,
-			Image:     post.Image,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-			DeletedAt: post.DeletedAt,
-		},
+		Data:    response,
 	})
 }
 
 // GetAllPosts godoc
 // @Summary Get all posts
-// @Description Get list of all posts
+// @Description Get list of all posts. With a token, posts of blocked and muted users and posts containing muted words are left out.
 // @Tags Posts
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=[]dtos.PostResponse}
 // @Router /posts [get]
 func (ph *PostHandler) GetAllPosts(c *gin.Context) {
-	posts, err := ph.postRepo.GetAllPosts(c.Request.Context())
+	// the token is optional here, without one only public posts are shown and
+	// nothing is muted
+	viewerId, _ := utils.GetUserFromCtx(c)
+
+	posts, err := ph.postRepo.GetAllPosts(c.Request.Context(), viewerId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch posts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get posts successfully",
+		Data:    posts,
+	})
+}
+
+// GetUserPosts godoc
+// @Summary Get user posts
+// @Description Get a page of the posts on a user's profile that the viewer can see. By default the user's posts are listed with pinned ones first; filter=replies adds the posts they commented on and filter=media keeps only posts with images.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "User ID"
+// @Param filter query string false "posts (default), replies or media"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.PostResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 500 {object} dtos.Response
+// @Router /users/{id}/posts [get]
+func (ph *PostHandler) GetUserPosts(c *gin.Context) {
+	userIdStr := c.Param("id")
+	userId, err := strconv.Atoi(userIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid user id",
+		})
+		return
+	}
+
+	filter := c.DefaultQuery("filter", models.ProfilePosts)
+	if filter != models.ProfilePosts && filter != models.ProfileReplies && filter != models.ProfileMedia {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid filter, use posts, replies or media",
+		})
+		return
+	}
+
+	viewerId, _ := utils.GetUserFromCtx(c)
+	limit, offset := utils.GetPagination(c)
+
+	posts, err := ph.postRepo.GetPostsByUser(c.Request.Context(), userId, viewerId, filter, limit, offset)
 	if err != nil {
 		log.Println(err.Error())
 		c.JSON(http.StatusInternalServerError, dtos.Response{
@@ -135,6 +353,7 @@ func (ph *PostHandler) GetAllPosts(c *gin.Context) {
 // @Description Get a single post by its ID
 // @Tags Posts
 // @Produce json
+// @Security BearerAuth
 // @Param postId path int true "Post ID"
 // @Success 200 {object} dtos.Response{data=dtos.PostResponse}
 // @Failure 404 {object} dtos.Response
@@ -151,7 +370,9 @@ func (ph *PostHandler) GetPostByID(c *gin.Context) {
 		return
 	}
 
-	post, err := ph.postRepo.GetPostByID(c.Request.Context(), postId)
+	viewerId, _ := utils.GetUserFromCtx(c)
+
+	post, err := ph.postRepo.GetPostByID(c.Request.Context(), postId, viewerId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, dtos.Response{
 			Code:    http.StatusNotFound,
@@ -165,30 +386,95 @@ func (ph *PostHandler) GetPostByID(c *gin.Context) {
 		Code:    http.StatusOK,
 		Success: true,
 		Message: "Get post successfully",
-		Data: dtos.PostResponse{
-			ID:        post.ID,
-			UserID:    post.UserID,
-			Content:   post.Content,
-			Image:     post.Image,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-		},
+		Data:    post,
+	})
+}
+
+// GetRevisions godoc
+// @Summary Get post revisions
+// @Description Get the versions of a post that edits replaced, newest first. Anyone who can see the post can see its revisions.
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param postId path int true "Post ID"
+// @Success 200 {object} dtos.Response{data=[]dtos.PostRevisionResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/revisions [get]
+func (ph *PostHandler) GetRevisions(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	viewerId, _ := utils.GetUserFromCtx(c)
+
+	if _, err := ph.postRepo.GetPostByID(c.Request.Context(), postId, viewerId); err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Post not found",
+		})
+		return
+	}
+
+	revisions, err := ph.postRepo.GetRevisions(c.Request.Context(), postId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get revisions",
+		})
+		return
+	}
+
+	response := make([]dtos.PostRevisionResponse, len(revisions))
+	for i, r := range revisions {
+		response[i] = dtos.PostRevisionResponse{
+			Revision:       r.Revision,
+			Content:        r.Content,
+			ContentWarning: r.ContentWarning,
+			Media:          postMediaResponses(ph.store, r.Media),
+			CreatedAt:      r.CreatedAt,
+			ReplacedAt:     r.ReplacedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get revisions successfully",
+		Data:    response,
 	})
 }
 
 // UpdatePost godoc
 // @Summary Update post
-// @Description Update a post by ID
+// @Description Update a post by ID: its content, audience and gallery (add, remove and reorder images). New content is screened like on creation; held content is hidden until a moderator reviews it (202). Changing the content or gallery of a published post keeps the previous version as a revision and, when an edit window is configured, is refused once it has passed (403). Drafts and scheduled posts can be changed freely. The link preview follows the first link of new content.
 // @Tags Posts
 // @Accept multipart/form-data
 // @Produce json
 // @Param postId path int true "Post ID"
 // @Param content formData string false "Post content"
-// @Param image formData file false "Post image"
+// @Param media_ids formData []int false "IDs of images uploaded through POST /media, added to the end of the gallery" collectionFormat(multi)
+// @Param alt_texts formData []string false "Alt text of each added image, in the same order" collectionFormat(multi)
+// @Param remove_media formData []int false "IDs of images to remove" collectionFormat(multi)
+// @Param media_order formData []int false "IDs of every kept image in their new order" collectionFormat(multi)
+// @Param visibility formData string false "Audience: public, followers, close_friends, only_me or unlisted"
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response{data=dtos.PostResponse}
+// @Success 202 {object} dtos.Response{data=dtos.PostResponse}
 // @Failure 400 {object} dtos.Response
 // @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
 // @Router /posts/{postId} [patch]
 func (ph *PostHandler) UpdatePost(c *gin.Context) {
 	postIdStr := c.Param("id")
@@ -212,7 +498,7 @@ func (ph *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	existingPost, err := ph.postRepo.GetPostByID(c.Request.Context(), postId)
+	existingPost, err := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, dtos.Response{
 			Code:    http.StatusNotFound,
@@ -241,30 +527,37 @@ func (ph *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	var imagePath *string
-	if body.Image != nil {
-		filename, err := utils.FileUpload(c, body.Image, "posts")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, dtos.Response{
-				Code:    http.StatusBadRequest,
-				Success: false,
-				Message: err.Error(),
-			})
+	if body.Visibility != nil && !isPostVisibility(*body.Visibility) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid visibility",
+		})
+		return
+	}
+
+	var screened utils.ScreenResult
+	if body.Content != nil && *body.Content != "" {
+		result, ok := screenContent(c, ph.screener, *body.Content)
+		if !ok {
 			return
 		}
-		imagePath = &filename
+		screened = result
 	}
 
 	updated := models.Post{ID: postId}
 
 	if body.Content != nil && *body.Content != "" {
 		updated.Content = body.Content
+		updated.ContentWarning, updated.HiddenAt = screenedFields(screened)
+		updated.LinkURL = firstLink(*body.Content)
 	}
-	if imagePath != nil {
-		updated.Image = imagePath
+	if body.Visibility != nil {
+		updated.Visibility = *body.Visibility
 	}
+	mediaChanged := len(body.MediaIDs) > 0 || len(body.RemoveMedia) > 0 || len(body.MediaOrder) > 0
 
-	if updated.Content == nil && updated.Image == nil {
+	if updated.Content == nil && updated.Visibility == "" && !mediaChanged {
 		c.JSON(http.StatusBadRequest, dtos.Response{
 			Code:    http.StatusBadRequest,
 			Success: false,
@@ -273,7 +566,52 @@ func (ph *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	if err := ph.postRepo.UpdatePost(c.Request.Context(), &updated); err != nil {
+	if mediaChanged {
+		current, err := ph.postRepo.GetMedia(c.Request.Context(), postId)
+		if err != nil {
+			log.Println(err.Error())
+			c.JSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Failed to update post",
+			})
+			return
+		}
+
+		kept, ok := arrangePostMedia(c, current, body.RemoveMedia, body.MediaOrder)
+		if !ok {
+			return
+		}
+		if len(kept)+len(body.MediaIDs) > models.MaxPostMedia {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: fmt.Sprintf("A post can have at most %d images", models.MaxPostMedia),
+			})
+			return
+		}
+
+		added, ok := pendingPostMedia(c, ph.uploadRepo, userId, body.MediaIDs, body.AltTexts)
+		if !ok {
+			return
+		}
+		updated.Media = append(kept, added...)
+	}
+
+	if err := ph.postRepo.UpdatePost(c.Request.Context(), &updated, ph.policy.EditWindow); err != nil {
+		log.Println(err.Error())
+		if errors.Is(err, repos.ErrUploadUnavailable) {
+			mediaUnavailable(c)
+			return
+		}
+		if errors.Is(err, repos.ErrEditWindowClosed) {
+			c.JSON(http.StatusForbidden, dtos.Response{
+				Code:    http.StatusForbidden,
+				Success: false,
+				Message: "This post can no longer be edited, only its audience can change",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
 			Success: false,
@@ -282,7 +620,31 @@ func (ph *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	postAfterUpdate, _ := ph.postRepo.GetPostByID(c.Request.Context(), postId)
+	held := updated.HiddenAt != nil
+	if updated.LinkURL != nil {
+		ph.linkRepo.UnfurlInBackground(*updated.LinkURL)
+	}
+	if updated.Content != nil {
+		_, mentioned, err := ph.mentionRepo.SaveMentions(c.Request.Context(), postId, nil, userId, *updated.Content)
+		if err != nil {
+			log.Println("Failed to save mentions.\nCause:", err.Error())
+		} else if !held && existingPost.Status == models.PostPublished {
+			notifyMentions(c.Request.Context(), ph.notificationRepo, userId, postId, nil, mentioned)
+		}
+	}
+
+	postAfterUpdate, _ := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+
+	if held {
+		holdForReview(c, ph.reportRepo, models.ReportTargetPost, postId, screened)
+		c.JSON(http.StatusAccepted, dtos.Response{
+			Code:    http.StatusAccepted,
+			Success: true,
+			Message: "Post is held for review",
+			Data:    postAfterUpdate,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
@@ -294,7 +656,7 @@ func (ph *PostHandler) UpdatePost(c *gin.Context) {
 
 // DeletePost godoc
 // @Summary Delete post
-// @Description Delete a post by ID
+// @Description Delete a post by ID. It goes to the trash, where it can be restored until it is purged for good. A pinned post is unpinned.
 // @Tags Posts
 // @Produce json
 // @Param postId path int true "Post ID"
@@ -324,7 +686,7 @@ func (ph *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	existingPost, err := ph.postRepo.GetPostByID(c.Request.Context(), postId)
+	existingPost, err := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, dtos.Response{
 			Code:    http.StatusNotFound,
@@ -358,3 +720,704 @@ func (ph *PostHandler) DeletePost(c *gin.Context) {
 		Message: "Post deleted successfully",
 	})
 }
+
+// GetTrash godoc
+// @Summary Get trash
+// @Description Get the posts the user deleted that can still be restored, with the time each is purged for good
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.PostResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 500 {object} dtos.Response
+// @Router /posts/trash [get]
+func (ph *PostHandler) GetTrash(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	posts, err := ph.postRepo.GetTrash(c.Request.Context(), userId, ph.policy.TrashRetention)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get trash",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get trash successfully",
+		Data:    posts,
+	})
+}
+
+// RestorePost godoc
+// @Summary Restore post
+// @Description Bring back a deleted post from the trash before it is purged
+// @Tags Posts
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.PostResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/restore [post]
+func (ph *PostHandler) RestorePost(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := ph.postRepo.RestorePost(c.Request.Context(), postId, userId, ph.policy.TrashRetention); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Post not found in trash",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to restore post",
+		})
+		return
+	}
+
+	post, _ := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Post restored successfully",
+		Data:    post,
+	})
+}
+
+// GetDrafts godoc
+// @Summary Get drafts
+// @Description Get the user's drafts and scheduled posts, the next to be published first
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.PostResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 500 {object} dtos.Response
+// @Router /posts/drafts [get]
+func (ph *PostHandler) GetDrafts(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	posts, err := ph.postRepo.GetDrafts(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get drafts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get drafts successfully",
+		Data:    posts,
+	})
+}
+
+// SchedulePost godoc
+// @Summary Schedule post
+// @Description Schedule a draft, or move a scheduled post to another time
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param body body dtos.PostScheduleRequest true "When to publish the post, in the future"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.PostResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /posts/{postId}/schedule [patch]
+func (ph *PostHandler) SchedulePost(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var body dtos.PostScheduleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+	if !validPublishAt(c, body.PublishAt) {
+		return
+	}
+
+	ph.setSchedule(c, postId, userId, &body.PublishAt, "Post scheduled successfully")
+}
+
+// CancelSchedule godoc
+// @Summary Cancel scheduled post
+// @Description Stop a scheduled post from being published, turning it back into a draft
+// @Tags Posts
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.PostResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/schedule [delete]
+func (ph *PostHandler) CancelSchedule(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	ph.setSchedule(c, postId, userId, nil, "Schedule cancelled, the post is a draft again")
+}
+
+// setSchedule sets when an unpublished post of the user is published and
+// writes the response with the post.
+func (ph *PostHandler) setSchedule(c *gin.Context, postId, userId int, publishAt *time.Time, message string) {
+	if err := ph.postRepo.SchedulePost(c.Request.Context(), postId, userId, publishAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Draft or scheduled post not found",
+			})
+			return
+		}
+		if errors.Is(err, repos.ErrPollWindow) {
+			pollOutsideWindow(c)
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to schedule post",
+		})
+		return
+	}
+
+	post, _ := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: message,
+		Data:    post,
+	})
+}
+
+// PublishPost godoc
+// @Summary Publish post
+// @Description Publish a draft or scheduled post right away. Mentioned users and followers are told about it now.
+// @Tags Posts
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.PostResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /posts/{postId}/publish [post]
+func (ph *PostHandler) PublishPost(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	hidden, err := ph.postRepo.PublishPost(c.Request.Context(), postId, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Draft or scheduled post not found",
+			})
+			return
+		}
+		if errors.Is(err, repos.ErrPollWindow) {
+			pollOutsideWindow(c)
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to publish post",
+		})
+		return
+	}
+
+	post, _ := ph.postRepo.GetPostByID(c.Request.Context(), postId, userId)
+	// a held post is announced once a moderator lets it through
+	if post != nil && !hidden {
+		ph.announcePost(c.Request.Context(), *post)
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Post published successfully",
+		Data:    post,
+	})
+}
+
+// PinPost godoc
+// @Summary Pin post
+// @Description Pin one of the user's published posts to the top of their profile. Only a few posts can be pinned at a time (409 beyond that).
+// @Tags Posts
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /posts/{postId}/pin [post]
+func (ph *PostHandler) PinPost(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := ph.postRepo.PinPost(c.Request.Context(), postId, userId, ph.policy.MaxPinned); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Post not found",
+			})
+			return
+		}
+		if errors.Is(err, repos.ErrPinLimit) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: fmt.Sprintf("You can pin at most %d posts, unpin one first", ph.policy.MaxPinned),
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to pin post",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Post pinned successfully",
+	})
+}
+
+// UnpinPost godoc
+// @Summary Unpin post
+// @Description Take a post off the pins of the user's profile
+// @Tags Posts
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /posts/{postId}/pin [delete]
+func (ph *PostHandler) UnpinPost(c *gin.Context) {
+	postIdStr := c.Param("id")
+	postId, err := strconv.Atoi(postIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid post id",
+		})
+		return
+	}
+
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	if err := ph.postRepo.UnpinPost(c.Request.Context(), postId, userId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Pinned post not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to unpin post",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Post unpinned successfully",
+	})
+}
+
+// StartScheduler publishes scheduled posts whose time has come every
+// ScheduleInterval of the post policy in the background, announcing each one
+// as it goes out.
+func (ph *PostHandler) StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(ph.policy.ScheduleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ph.publishDuePosts()
+		}
+	}()
+}
+
+func (ph *PostHandler) publishDuePosts() {
+	c, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	posts, err := ph.postRepo.PublishDuePosts(c)
+	if err != nil {
+		log.Println("Publishing scheduled posts failed:", err)
+		return
+	}
+	for _, p := range posts {
+		if p.HiddenAt != nil {
+			continue
+		}
+		post, err := ph.postRepo.GetPostByID(c, p.ID, p.UserID)
+		if err != nil {
+			log.Println("Failed to get published post.\nCause:", err.Error())
+			continue
+		}
+		ph.announcePost(c, *post)
+	}
+}
+
+// announcePost tells the users mentioned in a post that was just published and
+// the author's followers, and pushes it into the followers' live feed.
+func (ph *PostHandler) announcePost(c context.Context, post dtos.PostResponse) {
+	mentioned := []int{}
+	for _, m := range post.Mentions {
+		if !slices.Contains(mentioned, m.UserID) {
+			mentioned = append(mentioned, m.UserID)
+		}
+	}
+	notifyMentions(c, ph.notificationRepo, post.UserID, post.ID, nil, mentioned)
+	notifyFollowers(c, ph.notificationRepo, post)
+	ph.eventRepo.PublishNewPost(post)
+}
+
+// validPublishAt checks that a post is scheduled for the future. It writes the
+// error response and returns false otherwise.
+func validPublishAt(c *gin.Context, publishAt time.Time) bool {
+	if !publishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "publish_at must be in the future",
+		})
+		return false
+	}
+	return true
+}
+
+// firstLink returns the link of content to preview, or nil when it has none.
+func firstLink(content string) *string {
+	if link := utils.FirstLink(content); link != "" {
+		return &link
+	}
+	return nil
+}
+
+func isPostVisibility(v string) bool {
+	for _, pv := range models.PostVisibilities {
+		if pv == v {
+			return true
+		}
+	}
+	return false
+}
+
+// maxAltTextLength is the longest alt text an image can have, in characters.
+const maxAltTextLength = 1000
+
+// pendingPostMedia turns the uploads a user put into a post into its images,
+// each with the alt text at the same position. The uploads are attached when
+// the post is saved. It writes the error response and returns false when an
+// upload is unknown, expired or listed twice.
+func pendingPostMedia(c *gin.Context, uploadRepo *repos.UploadRepo, userId int, ids []int, altTexts []string) ([]models.PostMedia, bool) {
+	if len(altTexts) > len(ids) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "There are more alt texts than images",
+		})
+		return nil, false
+	}
+	for _, altText := range altTexts {
+		if utf8.RuneCountInString(altText) > maxAltTextLength {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: fmt.Sprintf("Alt text can be at most %d characters", maxAltTextLength),
+			})
+			return nil, false
+		}
+	}
+	if len(ids) == 0 {
+		return nil, true
+	}
+
+	uploads, err := uploadRepo.GetPendingUploads(c.Request.Context(), userId, ids)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get media",
+		})
+		return nil, false
+	}
+	byId := map[int]models.MediaUpload{}
+	for _, u := range uploads {
+		byId[u.ID] = u
+	}
+
+	media := make([]models.PostMedia, 0, len(ids))
+	for i, id := range ids {
+		u, ok := byId[id]
+		if !ok {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: fmt.Sprintf("Media %d is not an uploaded image waiting for a post", id),
+			})
+			return nil, false
+		}
+		// a second mention of the same id finds it gone
+		delete(byId, id)
+
+		m := models.PostMedia{
+			Filename: *u.Filename,
+			Variants: u.Variants,
+			UploadID: u.ID,
+		}
+		if i < len(altTexts) && strings.TrimSpace(altTexts[i]) != "" {
+			altText := strings.TrimSpace(altTexts[i])
+			m.AltText = &altText
+		}
+		media = append(media, m)
+	}
+	return media, true
+}
+
+func pollOutsideWindow(c *gin.Context) {
+	c.JSON(http.StatusConflict, dtos.Response{
+		Code:    http.StatusConflict,
+		Success: false,
+		Message: fmt.Sprintf("The poll would be closed or open longer than %d days when the post is published, choose another publish time", int(models.MaxPollDuration.Hours()/24)),
+	})
+}
+
+func mediaUnavailable(c *gin.Context) {
+	c.JSON(http.StatusConflict, dtos.Response{
+		Code:    http.StatusConflict,
+		Success: false,
+		Message: "Some media expired or was used in another post, upload it again",
+	})
+}
+
+// arrangePostMedia drops the removed images from the current gallery and puts
+// the rest in the requested order. The order has to list every kept image
+// once. It writes the error response and returns false otherwise.
+func arrangePostMedia(c *gin.Context, current []models.PostMedia, remove, order []int) ([]models.PostMedia, bool) {
+	byId := map[int]models.PostMedia{}
+	for _, m := range current {
+		byId[m.ID] = m
+	}
+
+	for _, id := range remove {
+		if _, ok := byId[id]; !ok {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: fmt.Sprintf("Image %d is not part of this post", id),
+			})
+			return nil, false
+		}
+		delete(byId, id)
+	}
+
+	if len(order) == 0 {
+		kept := []models.PostMedia{}
+		for _, m := range current {
+			if _, ok := byId[m.ID]; ok {
+				kept = append(kept, m)
+			}
+		}
+		return kept, true
+	}
+
+	kept := make([]models.PostMedia, 0, len(order))
+	for _, id := range order {
+		m, ok := byId[id]
+		if !ok {
+			break
+		}
+		kept = append(kept, m)
+		delete(byId, id)
+	}
+	if len(kept) != len(order) || len(byId) > 0 {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "media_order must list every kept image once",
+		})
+		return nil, false
+	}
+	return kept, true
+}
+
+func postMediaResponses(store storage.Storage, media []models.PostMedia) []dtos.PostMediaResponse {
+	response := make([]dtos.PostMediaResponse, len(media))
+	for i, m := range media {
+		utils.SetImageURLs(store, m.Variants)
+		response[i] = dtos.PostMediaResponse{
+			ID:       m.ID,
+			Position: m.Position,
+			Filename: m.Filename,
+			Variants: m.Variants,
+			AltText:  m.AltText,
+		}
+	}
+	return response
+}
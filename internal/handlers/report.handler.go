@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type ReportHandler struct {
+	reportRepo *repos.ReportRepo
+}
+
+func NewReportHandler(rr *repos.ReportRepo) *ReportHandler {
+	return &ReportHandler{reportRepo: rr}
+}
+
+// CreateReport godoc
+// @Summary Report content
+// @Description Report a post, comment or user to the moderators. A user can report the same target only once.
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param body body dtos.ReportRequest true "Report (reason: spam, harassment, hate_speech, violence, nudity, misinformation, other)"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.ReportResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Failure 429 {object} dtos.Response
+// @Router /reports [post]
+func (rh *ReportHandler) CreateReport(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var req dtos.ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !isReportReason(req.Reason) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid report reason",
+		})
+		return
+	}
+
+	ownerId, _, err := rh.reportRepo.TargetOwner(c.Request.Context(), req.TargetType, req.TargetID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Reported content not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create report",
+		})
+		return
+	}
+
+	if ownerId == userId {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "You cannot report yourself",
+		})
+		return
+	}
+
+	report := models.Report{
+		ReporterID: &userId,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	}
+	if err := rh.reportRepo.CreateReport(c.Request.Context(), &report); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "You have already reported this",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create report",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Report sent successfully",
+		Data: dtos.ReportResponse{
+			ID:         report.ID,
+			ReporterID: report.ReporterID,
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			Reason:     report.Reason,
+			Details:    report.Details,
+			Status:     report.Status,
+			CreatedAt:  report.CreatedAt,
+		},
+	})
+}
+
+func isReportReason(r string) bool {
+	for _, rr := range models.ReportReasons {
+		if rr == r {
+			return true
+		}
+	}
+	return false
+}
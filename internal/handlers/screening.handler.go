@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type ScreeningHandler struct {
+	screeningRepo *repos.ScreeningRepo
+}
+
+func NewScreeningHandler(sr *repos.ScreeningRepo) *ScreeningHandler {
+	return &ScreeningHandler{screeningRepo: sr}
+}
+
+// GetRules godoc
+// @Summary Get screening rules
+// @Description Get the blocked words, regular expressions and link domains new posts and comments are screened against. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.ScreeningRuleResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Router /admin/screening-rules [get]
+func (sh *ScreeningHandler) GetRules(c *gin.Context) {
+	rules, err := sh.screeningRepo.GetRules(c.Request.Context())
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch screening rules",
+		})
+		return
+	}
+
+	response := make([]dtos.ScreeningRuleResponse, len(rules))
+	for i, r := range rules {
+		response[i] = dtos.ScreeningRuleResponse{
+			ID:        r.ID,
+			Kind:      r.Kind,
+			Pattern:   r.Pattern,
+			Outcome:   r.Outcome,
+			CreatedBy: r.CreatedBy,
+			CreatedAt: r.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get screening rules successfully",
+		Data:    response,
+	})
+}
+
+// CreateRule godoc
+// @Summary Create screening rule
+// @Description Add a blocked word, regular expression or link domain. Content matching it is published behind a content warning (warn), hidden until reviewed (hold) or refused (reject). Moderators only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param body body dtos.ScreeningRuleRequest true "Rule"
+// @Security BearerAuth
+// @Success 201 {object} dtos.Response{data=dtos.ScreeningRuleResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 409 {object} dtos.Response
+// @Router /admin/screening-rules [post]
+func (sh *ScreeningHandler) CreateRule(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	var req dtos.ScreeningRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Kind == models.ScreenRegex {
+		if _, err := regexp.Compile(req.Pattern); err != nil {
+			c.JSON(http.StatusBadRequest, dtos.Response{
+				Code:    http.StatusBadRequest,
+				Success: false,
+				Message: "Invalid regular expression",
+			})
+			return
+		}
+	}
+	if strings.TrimSpace(req.Pattern) == "" {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Pattern cannot be empty",
+		})
+		return
+	}
+
+	rule := models.ScreeningRule{
+		Kind:      req.Kind,
+		Pattern:   req.Pattern,
+		Outcome:   req.Outcome,
+		CreatedBy: userId,
+	}
+	if err := sh.screeningRepo.CreateRule(c.Request.Context(), &rule); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "Screening rule already exists",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to create screening rule",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.Response{
+		Code:    http.StatusCreated,
+		Success: true,
+		Message: "Screening rule created successfully",
+		Data: dtos.ScreeningRuleResponse{
+			ID:        rule.ID,
+			Kind:      rule.Kind,
+			Pattern:   rule.Pattern,
+			Outcome:   rule.Outcome,
+			CreatedBy: rule.CreatedBy,
+			CreatedAt: rule.CreatedAt,
+		},
+	})
+}
+
+// DeleteRule godoc
+// @Summary Delete screening rule
+// @Description Delete a screening rule. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /admin/screening-rules/{id} [delete]
+func (sh *ScreeningHandler) DeleteRule(c *gin.Context) {
+	ruleId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid rule id",
+		})
+		return
+	}
+
+	rows, err := sh.screeningRepo.DeleteRule(c.Request.Context(), ruleId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to delete screening rule",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "Screening rule not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Screening rule deleted successfully",
+	})
+}
+
+// screenContent runs text through the screener. It writes the error response
+// itself when the text is rejected or cannot be screened.
+func screenContent(c *gin.Context, s utils.Screener, text string) (utils.ScreenResult, bool) {
+	result, err := s.Screen(c.Request.Context(), text)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to check content",
+		})
+		return result, false
+	}
+
+	if result.Outcome == models.ScreenReject {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Your content goes against our community guidelines",
+		})
+		return result, false
+	}
+	return result, true
+}
+
+// screenedFields returns the content warning and hidden_at a screening result
+// asks for. Only a non-nil hidden_at matters to the repos, which set the time
+// themselves.
+func screenedFields(result utils.ScreenResult) (*string, *time.Time) {
+	switch result.Outcome {
+	case models.ScreenWarn:
+		warning := models.ScreenContentWarning
+		return &warning, nil
+	case models.ScreenHold:
+		now := time.Now()
+		return nil, &now
+	}
+	return nil, nil
+}
+
+// holdForReview puts content held by screening in the moderation queue. A
+// failure is only logged, the content stays hidden either way.
+func holdForReview(c *gin.Context, rr *repos.ReportRepo, targetType string, targetId int, result utils.ScreenResult) {
+	details := "Held by screening: " + strings.Join(result.Matches, ", ")
+	if err := rr.CreateSystemReport(c.Request.Context(), targetType, targetId, details); err != nil {
+		log.Println("Failed to report held content.\nCause:", err.Error())
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type SuspensionHandler struct {
+	suspensionRepo *repos.SuspensionRepo
+	reportRepo     *repos.ReportRepo
+	userRepo       *repos.UserRepo
+}
+
+func NewSuspensionHandler(sr *repos.SuspensionRepo, rr *repos.ReportRepo, ur *repos.UserRepo) *SuspensionHandler {
+	return &SuspensionHandler{
+		suspensionRepo: sr,
+		reportRepo:     rr,
+		userRepo:       ur,
+	}
+}
+
+// SuspendUser godoc
+// @Summary Suspend user
+// @Description Suspend a user for duration_hours, or forever when it is empty. The user is logged out everywhere, cannot log in and their posts, comments and likes are hidden until the suspension ends. Moderators only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID to suspend"
+// @Param body body dtos.SuspendRequest true "Suspension"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.SuspensionResponse}
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /admin/users/{id}/suspend [post]
+func (sh *SuspensionHandler) SuspendUser(c *gin.Context) {
+	moderatorId, targetId, ok := targetFromCtx(c, sh.userRepo, "You cannot suspend yourself")
+	if !ok {
+		return
+	}
+
+	var req dtos.SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	suspension, err := sh.suspensionRepo.Suspend(c.Request.Context(), targetId, req.DurationHours, req.Reason)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to suspend user",
+		})
+		return
+	}
+
+	sh.record(c, moderatorId, models.ModerationSuspend, targetId, req.Reason)
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Suspended user successfully",
+		Data:    suspension,
+	})
+}
+
+// UnsuspendUser godoc
+// @Summary Lift suspension
+// @Description Lift the suspension of a user. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /admin/users/{id}/suspend [delete]
+func (sh *SuspensionHandler) UnsuspendUser(c *gin.Context) {
+	moderatorId, targetId, ok := targetFromCtx(c, sh.userRepo, "You cannot unsuspend yourself")
+	if !ok {
+		return
+	}
+
+	rows, err := sh.suspensionRepo.Unsuspend(c.Request.Context(), targetId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to lift suspension",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "This user is not suspended",
+		})
+		return
+	}
+
+	sh.record(c, moderatorId, models.ModerationUnsuspend, targetId, "Suspension lifted")
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Lifted suspension successfully",
+	})
+}
+
+// GetAppeals godoc
+// @Summary Get appeals
+// @Description Get suspension appeals oldest first, with the suspension they are about while it runs. Moderators only.
+// @Tags Moderation
+// @Produce json
+// @Param status query string false "open, accepted or rejected"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=[]dtos.AppealResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Router /admin/appeals [get]
+func (sh *SuspensionHandler) GetAppeals(c *gin.Context) {
+	limit, offset := utils.GetPagination(c)
+	appeals, err := sh.suspensionRepo.GetAppeals(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to fetch appeals",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get appeals successfully",
+		Data:    appeals,
+	})
+}
+
+// ResolveAppeal godoc
+// @Summary Resolve appeal
+// @Description Accept or reject an open appeal. Accepting it lifts the suspension. Moderators only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Appeal ID"
+// @Param body body dtos.ResolveAppealRequest true "Resolution"
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response
+// @Failure 400 {object} dtos.Response
+// @Failure 401 {object} dtos.Response
+// @Failure 403 {object} dtos.Response
+// @Failure 404 {object} dtos.Response
+// @Router /admin/appeals/{id} [patch]
+func (sh *SuspensionHandler) ResolveAppeal(c *gin.Context) {
+	moderatorId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	appealId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Invalid appeal id",
+		})
+		return
+	}
+
+	var req dtos.ResolveAppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userId, err := sh.suspensionRepo.ResolveAppeal(c.Request.Context(), appealId, moderatorId, req.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, dtos.Response{
+				Code:    http.StatusNotFound,
+				Success: false,
+				Message: "Open appeal not found",
+			})
+			return
+		}
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to resolve appeal",
+		})
+		return
+	}
+
+	if req.Status == models.AppealAccepted {
+		sh.record(c, moderatorId, models.ModerationUnsuspend, userId, "Appeal accepted")
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Appeal resolved successfully",
+	})
+}
+
+// record writes a suspension change to the moderation log. A failure is only
+// logged since the change itself already happened.
+func (sh *SuspensionHandler) record(c *gin.Context, moderatorId int, action string, userId int, reason string) {
+	err := sh.reportRepo.TakeAction(c.Request.Context(), &models.ModerationAction{
+		ModeratorID: moderatorId,
+		Action:      action,
+		TargetType:  models.ReportTargetUser,
+		TargetID:    userId,
+		Reason:      reason,
+	})
+	if err != nil {
+		log.Println("Failed to record moderation action.\nCause:", err.Error())
+	}
+}
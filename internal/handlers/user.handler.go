@@ -1,23 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
 	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type UserHandler struct {
-	userRepo *repos.UserRepo
+	userRepo  *repos.UserRepo
+	mediaRepo *repos.MediaRepo
+	store     storage.Storage
+	policies  utils.UploadPolicies
 }
 
-func NewUserHandler(ur *repos.UserRepo) *UserHandler {
+func NewUserHandler(ur *repos.UserRepo, mr *repos.MediaRepo, store storage.Storage, policies utils.UploadPolicies) *UserHandler {
 	return &UserHandler{
-		userRepo: ur,
+		userRepo:  ur,
+		mediaRepo: mr,
+		store:     store,
+		policies:  policies,
 	}
 }
 
@@ -83,13 +92,16 @@ func (uh *UserHandler) GetUserByID(c *gin.Context) {
 	}
 
 	response := dtos.UserResponse{
-		ID:        userId,
-		Name:      user.Name,
-		Email:     &user.Email,
-		Avatar:    user.Avatar,
-		Bio:       user.Bio,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:             userId,
+		Name:           user.Name,
+		Username:       user.Username,
+		Email:          &user.Email,
+		Avatar:         user.Avatar,
+		AvatarVariants: user.AvatarVariants,
+		Bio:            user.Bio,
+		IsPrivate:      user.IsPrivate,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
 	}
 
 	c.JSON(http.StatusOK, dtos.Response{
@@ -102,17 +114,21 @@ func (uh *UserHandler) GetUserByID(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary Update profile
-// @Description Update authenticated user's profile (name, avatar, bio)
+// @Description Update authenticated user's profile (name, username, avatar, bio, privacy). Making the account public accepts all pending follow requests.
 // @Tags Users
 // @Accept multipart/form-data
 // @Produce json
 // @Param name formData string false "Name"
-// @Param avatar formData file false "Avatar image"
+// @Param username formData string false "Username used for @mentions"
+// @Param avatar formData file false "Avatar image within the avatar policy (see GET /users/profile/storage), cropped square and stored in three sizes"
 // @Param bio formData string false "Bio"
+// @Param is_private formData bool false "Only approved followers can see posts and connections"
 // @Security BearerAuth
 // @Success 200 {object} dtos.Response
 // @Failure 400 {object} dtos.Response
 // @Failure 401 {object} dtos.Response
+// @Failure 413 {object} dtos.Response
+// @Failure 415 {object} dtos.Response
 // @Router /users/profile [patch]
 func (uh *UserHandler) UpdateUser(c *gin.Context) {
 	userId, err := utils.GetUserFromCtx(c)
@@ -137,26 +153,41 @@ func (uh *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var avatarPath *string
+	if body.Username != nil && !utils.IsValidUsername(*body.Username) {
+		c.JSON(http.StatusBadRequest, dtos.Response{
+			Code:    http.StatusBadRequest,
+			Success: false,
+			Message: "Username must be 3-30 characters of letters, numbers or underscores",
+		})
+		return
+	}
+
+	var (
+		avatarPath     *string
+		avatarVariants models.ImageVariants
+	)
 	file, err := c.FormFile("avatar")
 	if err == nil {
-		if filename, err := utils.FileUpload(c, file, "avatar"); err != nil {
-			c.JSON(http.StatusBadRequest, dtos.Response{
-				Code:    http.StatusBadRequest,
-				Success: false,
-				Message: err.Error(),
-			})
+		if !checkQuota(c, uh.mediaRepo, uh.policies, userId, file.Size) {
+			return
+		}
+		if variants, err := utils.FileUpload(c, uh.store, uh.mediaRepo, file, uh.policies.Policy(models.MediaAvatar)); err != nil {
+			uploadFailed(c, err)
 			return
 		} else {
+			filename := variants[models.ImageFull].Filename
 			avatarPath = &filename
+			avatarVariants = variants
 		}
 	}
 
 	updatedUser := models.User{
-		ID:     userId,
-		Name:   body.Name,
-		Avatar: avatarPath,
-		Bio:    body.Bio,
+		ID:             userId,
+		Name:           body.Name,
+		Username:       body.Username,
+		Avatar:         avatarPath,
+		AvatarVariants: avatarVariants,
+		Bio:            body.Bio,
 	}
 
 	if err := uh.userRepo.UpdateUser(
@@ -164,6 +195,15 @@ func (uh *UserHandler) UpdateUser(c *gin.Context) {
 		&updatedUser,
 	); err != nil {
 		log.Println(err.Error())
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, dtos.Response{
+				Code:    http.StatusConflict,
+				Success: false,
+				Message: "Username already taken",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, dtos.Response{
 			Code:    http.StatusInternalServerError,
 			Success: false,
@@ -172,9 +212,84 @@ func (uh *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if body.IsPrivate != nil {
+		if err := uh.userRepo.SetPrivate(c.Request.Context(), userId, *body.IsPrivate); err != nil {
+			log.Println(err.Error())
+			c.JSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Failed to update profile",
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, dtos.Response{
 		Code:    http.StatusOK,
 		Success: true,
 		Message: "Profile updated successfully",
 	})
 }
+
+// GetStorageUsage godoc
+// @Summary Get storage usage
+// @Description Get how much storage the user's images take out of their quota, by what they are used for, and the upload policy of every kind of media
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dtos.Response{data=dtos.StorageUsageResponse}
+// @Failure 401 {object} dtos.Response
+// @Failure 500 {object} dtos.Response
+// @Router /users/profile/storage [get]
+func (uh *UserHandler) GetStorageUsage(c *gin.Context) {
+	userId, err := utils.GetUserFromCtx(c)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusUnauthorized, dtos.Response{
+			Code:    http.StatusUnauthorized,
+			Success: false,
+			Message: "Unauthorized",
+		})
+		return
+	}
+
+	usage, err := uh.mediaRepo.GetStorageUsage(c.Request.Context(), userId)
+	if err != nil {
+		log.Println(err.Error())
+		c.JSON(http.StatusInternalServerError, dtos.Response{
+			Code:    http.StatusInternalServerError,
+			Success: false,
+			Message: "Failed to get storage usage",
+		})
+		return
+	}
+
+	quota := storageQuota(uh.policies, usage)
+	response := dtos.StorageUsageResponse{
+		UsedBytes:      usage.Total,
+		QuotaBytes:     quota,
+		RemainingBytes: max(quota-usage.Total, 0),
+		PostsBytes:     usage.Posts,
+		AvatarBytes:    usage.Avatar,
+		MessagesBytes:  usage.Messages,
+		PendingBytes:   usage.Pending,
+		Policies:       []dtos.UploadPolicyResponse{},
+	}
+	for _, kind := range []string{models.MediaAvatar, models.MediaPost, models.MediaMessage} {
+		policy := uh.policies.Policy(kind)
+		response.Policies = append(response.Policies, dtos.UploadPolicyResponse{
+			Kind:      kind,
+			MaxBytes:  policy.MaxBytes,
+			Types:     policy.Types,
+			MaxWidth:  policy.MaxWidth,
+			MaxHeight: policy.MaxHeight,
+		})
+	}
+
+	c.JSON(http.StatusOK, dtos.Response{
+		Code:    http.StatusOK,
+		Success: true,
+		Message: "Get storage usage successfully",
+		Data:    response,
+	})
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitPolicy allows Limit requests per Window for each client. Name keeps
+// the counters of different policies apart.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// slidingWindow keeps the timestamps of the requests made in the last window in
+// a sorted set. It records the request and returns 1 when there is room, or 0
+// otherwise, followed by the remaining requests and the milliseconds until the
+// oldest request leaves the window.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", key, 0, now - window)
+local count = redis.call("ZCARD", key)
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", key, now, ARGV[4])
+	redis.call("PEXPIRE", key, window)
+	count = count + 1
+	allowed = 1
+end
+
+local reset = window
+local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// RateLimit throttles requests with a sliding window in Redis. Clients are told
+// apart by user id when a token was checked before this middleware and by IP
+// otherwise. Requests carrying a service token listed in RATE_LIMIT_ALLOWLIST
+// (comma separated) in the X-Service-Token header are not limited. When Redis
+// is down requests are let through.
+func RateLimit(rdb *redis.Client, policy RateLimitPolicy) gin.HandlerFunc {
+	allowlist := []string{}
+	for _, t := range strings.Split(os.Getenv("RATE_LIMIT_ALLOWLIST"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			allowlist = append(allowlist, t)
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		if trustedService(ctx.GetHeader("X-Service-Token"), allowlist) {
+			ctx.Next()
+			return
+		}
+
+		client := "ip:" + ctx.ClientIP()
+		if userId, err := utils.GetUserFromCtx(ctx); err == nil {
+			client = "user:" + strconv.Itoa(userId)
+		}
+		key := fmt.Sprintf("Mosting:ratelimit:%s:%s", policy.Name, client)
+
+		now := time.Now()
+		member := strconv.FormatInt(now.UnixNano(), 10)
+		res, err := slidingWindow.Run(ctx, rdb, []string{key}, now.UnixMilli(), policy.Window.Milliseconds(), policy.Limit, member).Int64Slice()
+		if err != nil {
+			log.Println("Error when checking rate limit redis cache:", err)
+			ctx.Next()
+			return
+		}
+
+		allowed, remaining := res[0] == 1, res[1]
+		reset := (time.Duration(res[2])*time.Millisecond + time.Second - 1) / time.Second
+
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(policy.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		ctx.Header("X-RateLimit-Reset", strconv.FormatInt(int64(reset), 10))
+
+		if !allowed {
+			ctx.Header("Retry-After", strconv.FormatInt(int64(reset), 10))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, dtos.Response{
+				Code:    http.StatusTooManyRequests,
+				Success: false,
+				Message: "Too many requests, please try again later",
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func trustedService(token string, allowlist []string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range allowlist {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RequiredModerator lets through moderators and admins only. It must run after
+// RequiredToken.
+func RequiredModerator(db *pgxpool.Pool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId, err := utils.GetUserFromCtx(ctx)
+		if err != nil {
+			log.Println(err.Error())
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, dtos.Response{
+				Code:    http.StatusUnauthorized,
+				Success: false,
+				Message: "Unauthorized",
+			})
+			return
+		}
+
+		var role string
+		if err := db.QueryRow(ctx.Request.Context(), `SELECT role FROM users WHERE id=$1`, userId).Scan(&role); err != nil {
+			log.Println("Error when checking user role:", err)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, dtos.Response{
+				Code:    http.StatusForbidden,
+				Success: false,
+				Message: "You are not allowed to access this resource",
+			})
+			return
+		}
+
+		if role != models.RoleModerator && role != models.RoleAdmin {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, dtos.Response{
+				Code:    http.StatusForbidden,
+				Success: false,
+				Message: "You are not allowed to access this resource",
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
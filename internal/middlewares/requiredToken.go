@@ -6,13 +6,17 @@ import (
 	"strings"
 
 	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/Darari17/social-media/pkg"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func RequiredToken(rdb *redis.Client) gin.HandlerFunc {
+func RequiredToken(db *pgxpool.Pool, rdb *redis.Client) gin.HandlerFunc {
+	suspensions := repos.NewSuspensionRepo(db, rdb)
 	return func(ctx *gin.Context) {
 		bearerToken := ctx.GetHeader("Authorization")
 		if bearerToken == "" {
@@ -57,16 +61,8 @@ func RequiredToken(rdb *redis.Client) gin.HandlerFunc {
 			return
 		}
 
-		isBlacklist, err := rdb.Get(ctx, "Mosting:blacklist:"+token).Result()
-		if err == nil && isBlacklist == "true" {
-			log.Println("The token has logged out, please log in again")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, dtos.Response{
-				Code:    http.StatusUnauthorized,
-				Success: false,
-				Message: "The token has logged out, please log in again",
-			})
-			return
-		} else if err != redis.Nil && err != nil {
+		isBlacklist, err := utils.IsBlacklisted(ctx, rdb, token)
+		if err != nil {
 			log.Println("Error when checking blacklist redis cache:", err)
 			ctx.AbortWithStatusJSON(http.StatusInternalServerError, dtos.Response{
 				Code:    http.StatusInternalServerError,
@@ -75,6 +71,15 @@ func RequiredToken(rdb *redis.Client) gin.HandlerFunc {
 			})
 			return
 		}
+		if isBlacklist {
+			log.Println("The token has logged out, please log in again")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, dtos.Response{
+				Code:    http.StatusUnauthorized,
+				Success: false,
+				Message: "The token has logged out, please log in again",
+			})
+			return
+		}
 
 		claims := &pkg.Claims{}
 		if err := claims.VerifyToken(token); err != nil {
@@ -107,7 +112,42 @@ func RequiredToken(rdb *redis.Client) gin.HandlerFunc {
 			return
 		}
 
+		// fails closed: a user whose state cannot be read is not let in
+		suspended, err := suspensions.IsSuspended(ctx, claims.UserId)
+		if err != nil {
+			log.Println("Error when checking suspension:", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, dtos.Response{
+				Code:    http.StatusInternalServerError,
+				Success: false,
+				Message: "Internal server error",
+			})
+			return
+		}
+		if suspended {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, dtos.Response{
+				Code:    http.StatusForbidden,
+				Success: false,
+				Message: "Your account is suspended",
+			})
+			return
+		}
+
 		ctx.Set("claims", claims)
+		ctx.Set("token", token)
 		ctx.Next()
 	}
 }
+
+// OptionalToken authenticates the request when an Authorization header is
+// sent and lets anonymous requests through otherwise, for public endpoints
+// whose response depends on who is looking.
+func OptionalToken(db *pgxpool.Pool, rdb *redis.Client) gin.HandlerFunc {
+	required := RequiredToken(db, rdb)
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			ctx.Next()
+			return
+		}
+		required(ctx)
+	}
+}
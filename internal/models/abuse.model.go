@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Velocity signals tracked per user.
+const (
+	SignalFollowRate = "follow_rate"
+	// the same user followed again and again, i.e. follow/unfollow churn
+	SignalFollowChurn = "follow_churn"
+	SignalLikeRate    = "like_rate"
+)
+
+// What happens when a velocity rule is broken. Throttle refuses the request,
+// reauth ends the session so the user has to log in again and flag puts the
+// account in the moderation queue.
+const (
+	VelocityThrottle = "throttle"
+	VelocityReauth   = "reauth"
+	VelocityFlag     = "flag"
+)
+
+// VelocityRule fires when a signal goes over Limit within Window.
+type VelocityRule struct {
+	Signal string
+	Limit  int64
+	Window time.Duration
+	Action string
+}
+
+var VelocityRules = []VelocityRule{
+	{Signal: SignalFollowRate, Limit: 30, Window: time.Hour, Action: VelocityThrottle},
+	{Signal: SignalFollowRate, Limit: 50, Window: time.Hour, Action: VelocityFlag},
+	{Signal: SignalFollowChurn, Limit: 3, Window: 24 * time.Hour, Action: VelocityReauth},
+	{Signal: SignalFollowChurn, Limit: 5, Window: 24 * time.Hour, Action: VelocityFlag},
+	{Signal: SignalLikeRate, Limit: 120, Window: 10 * time.Minute, Action: VelocityThrottle},
+	{Signal: SignalLikeRate, Limit: 300, Window: 10 * time.Minute, Action: VelocityFlag},
+}
+
+type AbuseFlag struct {
+	ID            int       `db:"id"`
+	UserID        int       `db:"user_id"`
+	Signal        string    `db:"signal"`
+	Count         int       `db:"count"`
+	WindowSeconds int       `db:"window_seconds"`
+	Details       *string   `db:"details"`
+	CreatedAt     time.Time `db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	AppealOpen     = "open"
+	AppealAccepted = "accepted"
+	AppealRejected = "rejected"
+)
+
+type Appeal struct {
+	ID         int        `db:"id"`
+	UserID     int        `db:"user_id"`
+	Message    string     `db:"message"`
+	Status     string     `db:"status"`
+	ReviewedBy *int       `db:"reviewed_by"`
+	ReviewedAt *time.Time `db:"reviewed_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Bookmark is a post a user saved for later, optionally in one of their
+// collections.
+type Bookmark struct {
+	ID           int       `db:"id"`
+	UserID       int       `db:"user_id"`
+	PostID       int       `db:"post_id"`
+	CollectionID *int      `db:"collection_id"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type BookmarkCollection struct {
+	ID        int       `db:"id"`
+	UserID    int       `db:"user_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -11,4 +11,7 @@ type Comment struct {
 	Content   string     `db:"content"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at"`
+
+	ContentWarning *string    `db:"content_warning"`
+	HiddenAt       *time.Time `db:"hidden_at"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type Conversation struct {
+	ID        int        `db:"id"`
+	IsGroup   bool       `db:"is_group"`
+	Title     *string    `db:"title"`
+	CreatedBy int        `db:"created_by"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+type Message struct {
+	ID             int       `db:"id"`
+	ConversationID int       `db:"conversation_id"`
+	SenderID       int       `db:"sender_id"`
+	Content        *string   `db:"content"`
+	Image          *string   `db:"image"`
+	CreatedAt      time.Time `db:"created_at"`
+}
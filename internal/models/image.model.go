@@ -0,0 +1,21 @@
+package models
+
+// Sizes an uploaded image is stored in. Full is the image itself, scaled down
+// when it is very large; its filename is the one kept on the post or user.
+const (
+	ImageThumbnail = "thumbnail"
+	ImageMedium    = "medium"
+	ImageFull      = "full"
+)
+
+// ImageVariant is one stored size of an uploaded image. URL is not stored, it
+// is filled in when the image is returned.
+type ImageVariant struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url,omitempty"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// ImageVariants maps a size name to the image stored in that size.
+type ImageVariants map[string]ImageVariant
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// State of a fetched link preview.
+const (
+	LinkPreviewOK = "ok"
+	// the page could not be fetched or describes nothing, it is tried again
+	// after LinkPreviewRetry
+	LinkPreviewFailed = "failed"
+)
+
+// How long a fetched link preview is used before the page is fetched again.
+const (
+	LinkPreviewTTL   = 24 * time.Hour
+	LinkPreviewRetry = time.Hour
+)
+
+// LinkPreview is what a web page says about itself in its Open Graph or
+// Twitter card tags, kept by URL.
+type LinkPreview struct {
+	ID          int       `db:"id"`
+	URL         string    `db:"url"`
+	Status      string    `db:"status"`
+	Title       *string   `db:"title"`
+	Description *string   `db:"description"`
+	ImageURL    *string   `db:"image_url"`
+	SiteName    *string   `db:"site_name"`
+	FetchedAt   time.Time `db:"fetched_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// MediaFile is one stored file of an upload. Upload is the content addressed
+// name shared by all sizes of the same uploaded image.
+type MediaFile struct {
+	Key         string    `db:"key"`
+	Upload      string    `db:"upload"`
+	Variant     string    `db:"variant"`
+	Width       int       `db:"width"`
+	Height      int       `db:"height"`
+	SizeBytes   int64     `db:"size_bytes"`
+	ContentType string    `db:"content_type"`
+	CreatedAt   time.Time `db:"created_at"`
+	LastUsedAt  time.Time `db:"last_used_at"`
+}
+
+// Kinds of uploaded media, each with an upload policy of its own.
+const (
+	MediaAvatar  = "avatar"
+	MediaPost    = "post"
+	MediaMessage = "message"
+)
+
+// StorageUsage is how many bytes of storage a user's media takes, by what it
+// is used for. An image used in several places counts once in Total. Quota is
+// set for users with a quota of their own.
+type StorageUsage struct {
+	Posts    int64
+	Avatar   int64
+	Messages int64
+	Pending  int64
+	Total    int64
+	Quota    *int64
+}
+
+// State of a media upload.
+const (
+	// chunks are still coming in
+	UploadUploading = "uploading"
+	// stored and waiting to be attached to a post
+	UploadPending  = "pending"
+	UploadAttached = "attached"
+	// the image could not be processed
+	UploadFailed = "failed"
+)
+
+// MediaUploadTTL is how long an upload waits for its next chunk, or to be
+// attached to a post once complete, before it expires.
+const MediaUploadTTL = 24 * time.Hour
+
+// MediaUpload is an image uploaded ahead of the post it goes into. Filename and
+// Variants are set once the upload is complete.
+type MediaUpload struct {
+	ID        int           `db:"id"`
+	UserID    int           `db:"user_id"`
+	Status    string        `db:"status"`
+	SizeBytes int64         `db:"size_bytes"`
+	Filename  *string       `db:"filename"`
+	Variants  ImageVariants `db:"variants"`
+	PostID    *int          `db:"post_id"`
+	ExpiresAt time.Time     `db:"expires_at"`
+	CreatedAt time.Time     `db:"created_at"`
+
+	// bytes received so far, kept while chunks are coming in
+	Received int64 `db:"-"`
+}
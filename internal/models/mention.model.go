@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Mention struct {
+	ID              int       `db:"id"`
+	PostID          int       `db:"post_id"`
+	CommentID       *int      `db:"comment_id"`
+	AuthorID        int       `db:"author_id"`
+	MentionedUserID int       `db:"mentioned_user_id"`
+	Offset          int       `db:"start_offset"`
+	Length          int       `db:"length"`
+	CreatedAt       time.Time `db:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	NotificationMention = "mention"
+	NotificationLike    = "like"
+	NotificationComment = "comment"
+	NotificationFollow  = "follow"
+	// a followed user published a post
+	NotificationPost = "post"
+
+	NotificationFollowRequest = "follow_request"
+	NotificationFollowAccept  = "follow_accept"
+
+	// sent by moderators, they cannot be switched off and do not reveal who
+	// handled the case
+	NotificationWarning        = "warning"
+	NotificationReportResolved = "report_resolved"
+)
+
+// NotificationTypes lists every category a user can switch on or off.
+var NotificationTypes = []string{
+	NotificationLike,
+	NotificationComment,
+	NotificationFollow,
+	NotificationMention,
+	NotificationFollowRequest,
+	NotificationFollowAccept,
+	NotificationPost,
+}
+
+type Notification struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	ActorID   int        `db:"actor_id"`
+	Type      string     `db:"type"`
+	PostID    *int       `db:"post_id"`
+	CommentID *int       `db:"comment_id"`
+	ReadAt    *time.Time `db:"read_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
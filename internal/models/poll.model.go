@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Limits of a poll.
+const (
+	MinPollOptions = 2
+	MaxPollOptions = 4
+	// longest an option can be, in characters
+	MaxPollOptionLength = 100
+	// longest a poll can stay open
+	MaxPollDuration = 7 * 24 * time.Hour
+)
+
+// Poll is a question put to the readers of a post. Multiple lets voters pick
+// several options in their one vote.
+type Poll struct {
+	ID          int       `db:"id"`
+	PostID      int       `db:"post_id"`
+	Multiple    bool      `db:"multiple"`
+	VotersCount int       `db:"voters_count"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+
+	Options []PollOption `db:"-"`
+}
+
+type PollOption struct {
+	ID         int    `db:"id"`
+	PollID     int    `db:"poll_id"`
+	Position   int    `db:"position"`
+	Text       string `db:"text"`
+	VotesCount int    `db:"votes_count"`
+}
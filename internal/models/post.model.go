@@ -4,12 +4,92 @@ import (
 	"time"
 )
 
+// Audience of a post.
+const (
+	PostPublic       = "public"
+	PostFollowers    = "followers"
+	PostCloseFriends = "close_friends"
+	PostOnlyMe       = "only_me"
+	// unlisted posts are public by link and on the author's profile but are
+	// left out of feeds
+	PostUnlisted = "unlisted"
+)
+
+// State of a post. Drafts and scheduled posts are only seen by their author.
+const (
+	PostPublished = "published"
+	PostDraft     = "draft"
+	// published by the scheduler at PublishAt
+	PostScheduled = "scheduled"
+)
+
+// Filters of the posts listed on a profile.
+const (
+	// the user's posts, pinned ones first
+	ProfilePosts = "posts"
+	// the user's posts and the posts they commented on
+	ProfileReplies = "replies"
+	// the user's posts with images
+	ProfileMedia = "media"
+)
+
+// MaxPostMedia is the most images a post can carry.
+const MaxPostMedia = 4
+
+var PostVisibilities = []string{
+	PostPublic,
+	PostFollowers,
+	PostCloseFriends,
+	PostOnlyMe,
+	PostUnlisted,
+}
+
 type Post struct {
-	ID        int        `db:"id"`
-	UserID    int        `db:"user_id"`
-	Content   *string    `db:"content_text"`
-	Image     *string    `db:"content_image"`
-	CreatedAt time.Time  `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
-	DeletedAt *time.Time `db:"deleted_at"`
+	ID         int        `db:"id"`
+	UserID     int        `db:"user_id"`
+	Content    *string    `db:"content_text"`
+	Visibility string     `db:"visibility"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+	DeletedAt  *time.Time `db:"deleted_at"`
+
+	ContentWarning *string    `db:"content_warning"`
+	HiddenAt       *time.Time `db:"hidden_at"`
+	RevisionCount  int        `db:"revision_count"`
+	Status         string     `db:"status"`
+	PublishAt      *time.Time `db:"publish_at"`
+	PinnedAt       *time.Time `db:"pinned_at"`
+	LinkURL        *string    `db:"link_url"`
+
+	// the gallery in display order
+	Media []PostMedia `db:"-"`
+	Poll  *Poll       `db:"-"`
+}
+
+// PostMedia is one image of a post's gallery.
+type PostMedia struct {
+	ID        int           `db:"id"`
+	PostID    int           `db:"post_id"`
+	Position  int           `db:"position"`
+	Filename  string        `db:"filename"`
+	Variants  ImageVariants `db:"variants"`
+	AltText   *string       `db:"alt_text"`
+	CreatedAt time.Time     `db:"created_at"`
+
+	// the upload the image comes from when it is added to the post
+	UploadID int `db:"-"`
+}
+
+// PostRevision is a version of a post that an edit replaced. Revisions are
+// numbered from 1, the version the post was created with.
+type PostRevision struct {
+	ID             int       `db:"id"`
+	PostID         int       `db:"post_id"`
+	Revision       int       `db:"revision"`
+	Content        *string   `db:"content_text"`
+	ContentWarning *string   `db:"content_warning"`
+	CreatedAt      time.Time `db:"created_at"`
+	ReplacedAt     time.Time `db:"replaced_at"`
+
+	Media []PostMedia `db:"-"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+var ReportReasons = []string{
+	"spam",
+	"harassment",
+	"hate_speech",
+	"violence",
+	"nudity",
+	"misinformation",
+	"other",
+}
+
+// Reasons of reports filed by the system rather than a user: content held by
+// screening and accounts flagged by velocity rules. They have no reporter.
+const (
+	ReportReasonScreening = "screening"
+	ReportReasonVelocity  = "velocity"
+)
+
+const (
+	ReportOpen      = "open"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// Moderator actions. Hide and restore apply to posts and comments, warn and
+// suspend to the user behind the target. Unsuspend is only logged when a
+// suspension is lifted.
+const (
+	ModerationHide      = "hide"
+	ModerationRestore   = "restore"
+	ModerationWarn      = "warn"
+	ModerationSuspend   = "suspend"
+	ModerationUnsuspend = "unsuspend"
+)
+
+type Report struct {
+	ID         int        `db:"id"`
+	ReporterID *int       `db:"reporter_id"`
+	TargetType string     `db:"target_type"`
+	TargetID   int        `db:"target_id"`
+	Reason     string     `db:"reason"`
+	Details    *string    `db:"details"`
+	Status     string     `db:"status"`
+	ResolvedBy *int       `db:"resolved_by"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+type ModerationAction struct {
+	ID          int       `db:"id"`
+	ModeratorID int       `db:"moderator_id"`
+	Action      string    `db:"action"`
+	TargetType  string    `db:"target_type"`
+	TargetID    int       `db:"target_id"`
+	ReportID    *int      `db:"report_id"`
+	Reason      string    `db:"reason"`
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// What a screening rule matches: a whole word, a regular expression, or a
+// link to a domain and its subdomains.
+const (
+	ScreenWord   = "word"
+	ScreenRegex  = "regex"
+	ScreenDomain = "domain"
+)
+
+var ScreenKinds = []string{
+	ScreenWord,
+	ScreenRegex,
+	ScreenDomain,
+}
+
+// Outcomes of screening, from the mildest to the strictest. Warn publishes
+// the content behind a content warning, hold hides it until a moderator
+// reviews it and reject refuses it.
+const (
+	ScreenAllow  = "allow"
+	ScreenWarn   = "warn"
+	ScreenHold   = "hold"
+	ScreenReject = "reject"
+)
+
+var ScreenOutcomes = []string{
+	ScreenAllow,
+	ScreenWarn,
+	ScreenHold,
+	ScreenReject,
+}
+
+// ScreenContentWarning is shown on content published with the warn outcome.
+const ScreenContentWarning = "This content may be sensitive"
+
+type ScreeningRule struct {
+	ID        int       `db:"id"`
+	Kind      string    `db:"kind"`
+	Pattern   string    `db:"pattern"`
+	Outcome   string    `db:"outcome"`
+	CreatedBy int       `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type MutedWord struct {
+	ID        int       `db:"id"`
+	UserID    int       `db:"user_id"`
+	Word      string    `db:"word"`
+	CreatedAt time.Time `db:"created_at"`
+}
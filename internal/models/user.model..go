@@ -2,13 +2,29 @@ package models
 
 import "time"
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID        int        `db:"id"`
 	Name      *string    `db:"name"`
+	Username  *string    `db:"username"`
 	Email     string     `db:"email"`
 	Password  string     `db:"password"`
 	Avatar    *string    `db:"avatar"`
 	Bio       *string    `db:"bio"`
+	IsPrivate bool       `db:"is_private"`
+	Role      string     `db:"role"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at"`
+
+	// a suspension without an end is permanent
+	SuspendedAt      *time.Time `db:"suspended_at"`
+	SuspendedUntil   *time.Time `db:"suspended_until"`
+	SuspensionReason *string    `db:"suspension_reason"`
+
+	AvatarVariants ImageVariants `db:"avatar_variants"`
 }
@@ -51,6 +51,11 @@ func (ar *AuthRepo) Logout(c context.Context, token string) error {
 	return nil
 }
 
+// IsLoggedOut tells whether the token was logged out.
+func (ar *AuthRepo) IsLoggedOut(c context.Context, token string) (bool, error) {
+	return utils.IsBlacklisted(c, ar.rdb, token)
+}
+
 func (ar *AuthRepo) GetAllUsers(c context.Context) ([]dtos.UserResponse, error) {
 	query := "select id, name, email, avatar, bio, created_at, updated_at from users"
 
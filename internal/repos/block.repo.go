@@ -0,0 +1,159 @@
+package repos
+
+import (
+	"context"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type BlockRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewBlockRepo(db *pgxpool.Pool, rdb *redis.Client) *BlockRepo {
+	return &BlockRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// BlockUser blocks a user and removes the follow edges and pending follow
+// requests between the two in both directions.
+func (br *BlockRepo) BlockUser(c context.Context, blockerId, blockedId int) error {
+	tx, err := br.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	query := `INSERT INTO blocks (blocker_id, blocked_id, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (blocker_id, blocked_id) DO NOTHING`
+	if _, err := tx.Exec(c, query, blockerId, blockedId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM follows
+	         WHERE (follower_id = $1 AND following_id = $2)
+	            OR (follower_id = $2 AND following_id = $1)`
+	if _, err := tx.Exec(c, query, blockerId, blockedId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM follow_requests
+	         WHERE (requester_id = $1 AND target_id = $2)
+	            OR (requester_id = $2 AND target_id = $1)`
+	if _, err := tx.Exec(c, query, blockerId, blockedId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return err
+	}
+
+	br.clearFeedCache(c, blockerId, blockedId)
+	return nil
+}
+
+func (br *BlockRepo) UnblockUser(c context.Context, blockerId, blockedId int) (int64, error) {
+	cmdTag, err := br.db.Exec(c, `DELETE FROM blocks WHERE blocker_id=$1 AND blocked_id=$2`, blockerId, blockedId)
+	if err != nil {
+		return 0, err
+	}
+
+	br.clearFeedCache(c, blockerId, blockedId)
+	return cmdTag.RowsAffected(), nil
+}
+
+func (br *BlockRepo) MuteUser(c context.Context, muterId, mutedId int) error {
+	query := `INSERT INTO mutes (muter_id, muted_id, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (muter_id, muted_id) DO NOTHING`
+	if _, err := br.db.Exec(c, query, muterId, mutedId); err != nil {
+		return err
+	}
+
+	br.clearFeedCache(c, muterId)
+	return nil
+}
+
+func (br *BlockRepo) UnmuteUser(c context.Context, muterId, mutedId int) (int64, error) {
+	cmdTag, err := br.db.Exec(c, `DELETE FROM mutes WHERE muter_id=$1 AND muted_id=$2`, muterId, mutedId)
+	if err != nil {
+		return 0, err
+	}
+
+	br.clearFeedCache(c, muterId)
+	return cmdTag.RowsAffected(), nil
+}
+
+// IsBlocked reports whether either user blocked the other.
+func (br *BlockRepo) IsBlocked(c context.Context, userA, userB int) (bool, error) {
+	return br.AnyBlocked(c, userA, []int{userB})
+}
+
+// AnyBlocked reports whether userId and any of the others blocked each other.
+func (br *BlockRepo) AnyBlocked(c context.Context, userId int, others []int) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM blocks
+	              WHERE (blocker_id = $1 AND blocked_id = ANY($2))
+	                 OR (blocked_id = $1 AND blocker_id = ANY($2))
+	          )`
+
+	var exists bool
+	err := br.db.QueryRow(c, query, userId, others).Scan(&exists)
+	return exists, err
+}
+
+func (br *BlockRepo) GetBlockedUsers(c context.Context, userId int) ([]dtos.UserResponse, error) {
+	query := `
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
+		FROM blocks b
+		JOIN users u ON b.blocked_id = u.id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`
+	return br.getUsers(c, query, userId)
+}
+
+func (br *BlockRepo) GetMutedUsers(c context.Context, userId int) ([]dtos.UserResponse, error) {
+	query := `
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
+		FROM mutes m
+		JOIN users u ON m.muted_id = u.id
+		WHERE m.muter_id = $1
+		ORDER BY m.created_at DESC
+	`
+	return br.getUsers(c, query, userId)
+}
+
+func (br *BlockRepo) getUsers(c context.Context, query string, userId int) ([]dtos.UserResponse, error) {
+	rows, err := br.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []dtos.UserResponse{}
+	for rows.Next() {
+		var u dtos.UserResponse
+		if err := rows.Scan(&u.ID, &u.Name, &u.Username, &u.Email, &u.Avatar, &u.Bio, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// clearFeedCache drops the cached feeds of the given users so a block or mute
+// takes effect right away instead of after the cache expires.
+func (br *BlockRepo) clearFeedCache(c context.Context, userIds ...int) {
+	keys := make([]string, len(userIds))
+	for i, id := range userIds {
+		keys[i] = feedCacheKey(id)
+	}
+	br.rdb.Del(c, keys...)
+}
@@ -0,0 +1,170 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type BookmarkRepo struct {
+	db    *pgxpool.Pool
+	rdb   *redis.Client
+	posts *PostRepo
+}
+
+func NewBookmarkRepo(db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) *BookmarkRepo {
+	return &BookmarkRepo{
+		db:    db,
+		rdb:   rdb,
+		posts: NewPostRepo(db, rdb, store),
+	}
+}
+
+// bookmarkedBy tells whether the viewer bookmarked the post.
+func bookmarkedBy(postAlias, viewer string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM bookmarks bm WHERE bm.post_id = %[1]s.id AND bm.user_id = %[2]s)`, postAlias, viewer)
+}
+
+// bookmarkVisible hides bookmarks whose post was deleted or can no longer be
+// seen by the user who saved it. They are kept and show up again when the
+// post is restored or visible again.
+func bookmarkVisible(postAlias, viewer string) string {
+	return fmt.Sprintf(`%[1]s.deleted_at IS NULL AND %[1]s.status = 'published' AND %s AND %s AND %s AND %s AND %s`,
+		postAlias, notBlocked(postAlias+".user_id", viewer), visibleAccount(postAlias+".user_id", viewer),
+		postAudience(postAlias, viewer, false), notHidden(postAlias, viewer), notSuspended(postAlias+".user_id"))
+}
+
+// SaveBookmark bookmarks a post for the user, or moves the bookmark when the
+// post is already saved. It returns pgx.ErrNoRows when CollectionID is not a
+// collection of the user.
+func (br *BookmarkRepo) SaveBookmark(c context.Context, b *models.Bookmark) error {
+	query := `INSERT INTO bookmarks (user_id, post_id, collection_id, created_at)
+	          SELECT $1, $2, $3, now()
+	          WHERE $3::int IS NULL OR EXISTS (SELECT 1 FROM bookmark_collections WHERE id=$3 AND user_id=$1)
+	          ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = EXCLUDED.collection_id
+	          RETURNING id, created_at`
+	if err := br.db.QueryRow(c, query, b.UserID, b.PostID, b.CollectionID).Scan(&b.ID, &b.CreatedAt); err != nil {
+		return err
+	}
+
+	br.rdb.Del(c, feedCacheKey(b.UserID))
+	return nil
+}
+
+func (br *BookmarkRepo) DeleteBookmark(c context.Context, userId, postId int) (int64, error) {
+	cmdTag, err := br.db.Exec(c, `DELETE FROM bookmarks WHERE user_id=$1 AND post_id=$2`, userId, postId)
+	if err != nil {
+		return 0, err
+	}
+
+	br.rdb.Del(c, feedCacheKey(userId))
+	return cmdTag.RowsAffected(), nil
+}
+
+// GetBookmarks returns a page of the user's bookmarks, most recent first, in
+// one collection when collectionId is set. Bookmarks of posts that were
+// deleted or that the user cannot see anymore are left out.
+func (br *BookmarkRepo) GetBookmarks(c context.Context, userId int, collectionId *int, limit, offset int) ([]dtos.BookmarkResponse, error) {
+	query := fmt.Sprintf(`SELECT b.id, b.collection_id, b.created_at,
+	                 p.id, p.user_id, p.content_text, p.visibility, p.content_warning, p.revision_count, p.status, p.publish_at, p.pinned_at IS NOT NULL, p.created_at, p.updated_at, p.deleted_at
+	          FROM bookmarks b
+	          JOIN posts p ON p.id = b.post_id
+	          WHERE b.user_id=$1 AND ($2::int IS NULL OR b.collection_id=$2) AND %s
+	          ORDER BY b.created_at DESC, b.id DESC
+	          LIMIT $3 OFFSET $4`, bookmarkVisible("p", "$1"))
+	rows, err := br.db.Query(c, query, userId, collectionId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []dtos.BookmarkResponse{}
+	posts := []dtos.PostResponse{}
+	for rows.Next() {
+		var (
+			b dtos.BookmarkResponse
+			p dtos.PostResponse
+		)
+		if err := rows.Scan(&b.ID, &b.CollectionID, &b.CreatedAt,
+			&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+			return nil, err
+		}
+		p.BookmarkedByMe = true
+		bookmarks = append(bookmarks, b)
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := br.posts.attachDetails(c, posts, userId); err != nil {
+		return nil, err
+	}
+	for i := range bookmarks {
+		bookmarks[i].Post = posts[i]
+	}
+	return bookmarks, nil
+}
+
+// CreateCollection adds a bookmark collection. Names are unique per user
+// whatever their case; it returns pgx.ErrNoRows when the name is taken.
+func (br *BookmarkRepo) CreateCollection(c context.Context, bc *models.BookmarkCollection) error {
+	query := `INSERT INTO bookmark_collections (user_id, name, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (user_id, lower(name)) DO NOTHING
+	          RETURNING id, created_at`
+	return br.db.QueryRow(c, query, bc.UserID, bc.Name).Scan(&bc.ID, &bc.CreatedAt)
+}
+
+// GetCollections returns the user's collections by name, with how many of
+// their bookmarks can be seen.
+func (br *BookmarkRepo) GetCollections(c context.Context, userId int) ([]dtos.BookmarkCollectionResponse, error) {
+	query := fmt.Sprintf(`SELECT bc.id, bc.name, bc.created_at,
+	                 (SELECT count(*) FROM bookmarks b JOIN posts p ON p.id = b.post_id
+	                  WHERE b.collection_id = bc.id AND %s)
+	          FROM bookmark_collections bc
+	          WHERE bc.user_id=$1
+	          ORDER BY lower(bc.name)`, bookmarkVisible("p", "$1"))
+	rows, err := br.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []dtos.BookmarkCollectionResponse{}
+	for rows.Next() {
+		var bc dtos.BookmarkCollectionResponse
+		if err := rows.Scan(&bc.ID, &bc.Name, &bc.CreatedAt, &bc.Count); err != nil {
+			return nil, err
+		}
+		collections = append(collections, bc)
+	}
+	return collections, rows.Err()
+}
+
+// DeleteCollection removes a collection of the user. Its bookmarks are kept
+// outside of any collection.
+func (br *BookmarkRepo) DeleteCollection(c context.Context, userId, collectionId int) (int64, error) {
+	tx, err := br.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	cmdTag, err := tx.Exec(c, `DELETE FROM bookmark_collections WHERE id=$1 AND user_id=$2`, collectionId, userId)
+	if err != nil {
+		return 0, err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return 0, nil
+	}
+	if _, err := tx.Exec(c, `UPDATE bookmarks SET collection_id = NULL WHERE collection_id=$1`, collectionId); err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), tx.Commit(c)
+}
@@ -0,0 +1,69 @@
+package repos
+
+import (
+	"context"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type CloseFriendRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewCloseFriendRepo(db *pgxpool.Pool, rdb *redis.Client) *CloseFriendRepo {
+	return &CloseFriendRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+func (cr *CloseFriendRepo) AddCloseFriend(c context.Context, userId, friendId int) error {
+	query := `INSERT INTO close_friends (user_id, friend_id, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (user_id, friend_id) DO NOTHING`
+	if _, err := cr.db.Exec(c, query, userId, friendId); err != nil {
+		return err
+	}
+
+	// close friends posts appear in (or leave) the friend's feed
+	cr.rdb.Del(c, feedCacheKey(friendId))
+	return nil
+}
+
+func (cr *CloseFriendRepo) RemoveCloseFriend(c context.Context, userId, friendId int) (int64, error) {
+	cmdTag, err := cr.db.Exec(c, `DELETE FROM close_friends WHERE user_id=$1 AND friend_id=$2`, userId, friendId)
+	if err != nil {
+		return 0, err
+	}
+
+	cr.rdb.Del(c, feedCacheKey(friendId))
+	return cmdTag.RowsAffected(), nil
+}
+
+func (cr *CloseFriendRepo) GetCloseFriends(c context.Context, userId int) ([]dtos.UserResponse, error) {
+	query := `
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
+		FROM close_friends cf
+		JOIN users u ON cf.friend_id = u.id
+		WHERE cf.user_id = $1
+		ORDER BY cf.created_at DESC
+	`
+	rows, err := cr.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []dtos.UserResponse{}
+	for rows.Next() {
+		var u dtos.UserResponse
+		if err := rows.Scan(&u.ID, &u.Name, &u.Username, &u.Email, &u.Avatar, &u.Bio, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
@@ -2,6 +2,7 @@ package repos
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
@@ -16,20 +17,37 @@ func NewCommentRepo(db *pgxpool.Pool) *CommentRepo {
 	return &CommentRepo{db: db}
 }
 
+// CreateComment stores the comment. A comment with HiddenAt set is stored
+// hidden until a moderator reviews it.
 func (cr *CommentRepo) CreateComment(c context.Context, comment *models.Comment) error {
-	query := `INSERT INTO comments (user_id, post_id, content, created_at)
-	          VALUES ($1, $2, $3, now())`
-	_, err := cr.db.Exec(c, query, comment.UserID, comment.PostID, comment.Content)
-	return err
+	query := `INSERT INTO comments (user_id, post_id, content, content_warning, hidden_at, created_at)
+	          VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN now() END, now())
+	          RETURNING id, created_at, hidden_at`
+	return cr.db.QueryRow(c, query, comment.UserID, comment.PostID, comment.Content, comment.ContentWarning, comment.HiddenAt != nil).
+		Scan(&comment.ID, &comment.CreatedAt, &comment.HiddenAt)
 }
 
-func (cr *CommentRepo) GetCommentsByPost(c context.Context, postId int) ([]dtos.CommentResponse, error) {
-	query := `SELECT id, user_id, post_id, content, created_at, updated_at 
+func (cr *CommentRepo) GetCommentByID(c context.Context, commentId int) (*models.Comment, error) {
+	query := `SELECT id, user_id, post_id, content, content_warning, hidden_at, created_at, updated_at
+	          FROM comments
+	          WHERE id=$1`
+
+	var cm models.Comment
+	if err := cr.db.QueryRow(c, query, commentId).Scan(&cm.ID, &cm.UserID, &cm.PostID, &cm.Content, &cm.ContentWarning, &cm.HiddenAt, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &cm, nil
+}
+
+// GetCommentsByPost leaves out comments of users the viewer blocked, muted or
+// was blocked by, of suspended users, and comments hidden by a moderator.
+func (cr *CommentRepo) GetCommentsByPost(c context.Context, postId, viewerId int) ([]dtos.CommentResponse, error) {
+	query := fmt.Sprintf(`SELECT id, user_id, post_id, content, content_warning, created_at, updated_at 
 	          FROM comments 
-	          WHERE post_id=$1
-	          ORDER BY created_at ASC`
+	          WHERE post_id=$1 AND %s AND %s AND %s AND %s
+	          ORDER BY created_at ASC`, notBlocked("user_id", "$2"), notMuted("user_id", "$2"), notHidden("comments", "$2"), notSuspended("comments.user_id"))
 
-	rows, err := cr.db.Query(c, query, postId)
+	rows, err := cr.db.Query(c, query, postId, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -38,17 +56,36 @@ func (cr *CommentRepo) GetCommentsByPost(c context.Context, postId int) ([]dtos.
 	var comments []dtos.CommentResponse
 	for rows.Next() {
 		var cm dtos.CommentResponse
-		if err := rows.Scan(&cm.ID, &cm.UserID, &cm.PostID, &cm.Content, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
+		if err := rows.Scan(&cm.ID, &cm.UserID, &cm.PostID, &cm.Content, &cm.ContentWarning, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
 			return nil, err
 		}
 		comments = append(comments, cm)
 	}
+
+	ids := make([]int, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	mentions, err := loadMentions(c, cr.db, ids, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+		if comments[i].Mentions == nil {
+			comments[i].Mentions = []dtos.MentionResponse{}
+		}
+	}
 	return comments, nil
 }
 
-func (cr *CommentRepo) UpdateComment(c context.Context, commentId int, content string) error {
-	query := `UPDATE comments SET content=$1, updated_at=now() WHERE id=$2`
-	_, err := cr.db.Exec(c, query, content, commentId)
+// UpdateComment replaces the text and content warning of the comment, hiding
+// it for review when HiddenAt is set.
+func (cr *CommentRepo) UpdateComment(c context.Context, comment *models.Comment) error {
+	query := `UPDATE comments
+	          SET content=$1, content_warning=$2, hidden_at=CASE WHEN $3 THEN now() ELSE hidden_at END, updated_at=now()
+	          WHERE id=$4`
+	_, err := cr.db.Exec(c, query, comment.Content, comment.ContentWarning, comment.HiddenAt != nil, comment.ID)
 	return err
 }
 
@@ -0,0 +1,358 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// messageImageTTL is how long the signed link to a message image works.
+const messageImageTTL = time.Hour
+
+type ConversationRepo struct {
+	db    *pgxpool.Pool
+	store storage.Storage
+}
+
+func NewConversationRepo(db *pgxpool.Pool, store storage.Storage) *ConversationRepo {
+	return &ConversationRepo{
+		db:    db,
+		store: store,
+	}
+}
+
+// ImageURL returns a signed link to a message image. Message images are only
+// for the members of the conversation, so the link expires.
+func (cr *ConversationRepo) ImageURL(c context.Context, image *string) *string {
+	if image == nil {
+		return nil
+	}
+	url, err := cr.store.SignedURL(c, *image, messageImageTTL)
+	if err != nil {
+		log.Println("Failed to sign message image url:", err)
+		return nil
+	}
+	return &url
+}
+
+// FindDirectConversation returns the id of the existing 1:1 conversation
+// between two users, or nil when they never talked before.
+func (cr *ConversationRepo) FindDirectConversation(c context.Context, userA, userB int) (*int, error) {
+	query := `SELECT cv.id
+	          FROM conversations cv
+	          JOIN conversation_members a ON a.conversation_id = cv.id AND a.user_id = $1
+	          JOIN conversation_members b ON b.conversation_id = cv.id AND b.user_id = $2
+	          WHERE NOT cv.is_group
+	          LIMIT 1`
+
+	var id int
+	err := cr.db.QueryRow(c, query, userA, userB).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (cr *ConversationRepo) CountUsers(c context.Context, userIds []int) (int, error) {
+	var count int
+	err := cr.db.QueryRow(c, `SELECT count(*) FROM users WHERE id = ANY($1)`, userIds).Scan(&count)
+	return count, err
+}
+
+func (cr *ConversationRepo) CreateConversation(c context.Context, conv *models.Conversation, memberIds []int) error {
+	tx, err := cr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	query := `INSERT INTO conversations (is_group, title, created_by, created_at, updated_at)
+	          VALUES ($1, $2, $3, now(), now())
+	          RETURNING id, created_at, updated_at`
+	if err := tx.QueryRow(c, query, conv.IsGroup, conv.Title, conv.CreatedBy).Scan(&conv.ID, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
+		return err
+	}
+
+	for _, id := range memberIds {
+		query := `INSERT INTO conversation_members (conversation_id, user_id, joined_at) VALUES ($1, $2, now())`
+		if _, err := tx.Exec(c, query, conv.ID, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+func (cr *ConversationRepo) IsMember(c context.Context, conversationId, userId int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$1 AND user_id=$2)`
+	err := cr.db.QueryRow(c, query, conversationId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (cr *ConversationRepo) IsGroup(c context.Context, conversationId int) (bool, error) {
+	var isGroup bool
+	err := cr.db.QueryRow(c, `SELECT is_group FROM conversations WHERE id=$1`, conversationId).Scan(&isGroup)
+	return isGroup, err
+}
+
+func (cr *ConversationRepo) GetMemberIDs(c context.Context, conversationId int) ([]int, error) {
+	rows, err := cr.db.Query(c, `SELECT user_id FROM conversation_members WHERE conversation_id=$1`, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetConversations lists the user's conversations, most recently active
+// first, each with its last message and the number of unread messages.
+func (cr *ConversationRepo) GetConversations(c context.Context, userId, limit, offset int) ([]dtos.ConversationResponse, error) {
+	query := `
+		SELECT cv.id, cv.is_group, cv.title, cv.created_at, cv.updated_at,
+		       lm.id, lm.sender_id, lm.content, lm.image, lm.created_at,
+		       (SELECT count(*) FROM messages m
+		        WHERE m.conversation_id = cv.id AND m.sender_id <> $1
+		          AND m.id > COALESCE(me.last_read_message_id, 0))
+		FROM conversation_members me
+		JOIN conversations cv ON cv.id = me.conversation_id
+		LEFT JOIN LATERAL (
+			SELECT id, sender_id, content, image, created_at
+			FROM messages
+			WHERE conversation_id = cv.id
+			ORDER BY id DESC
+			LIMIT 1
+		) lm ON true
+		WHERE me.user_id = $1
+		ORDER BY cv.updated_at DESC, cv.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := cr.db.Query(c, query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []dtos.ConversationResponse{}
+	for rows.Next() {
+		var (
+			cv        dtos.ConversationResponse
+			msgId     *int
+			senderId  *int
+			content   *string
+			image     *string
+			createdAt *time.Time
+		)
+		if err := rows.Scan(&cv.ID, &cv.IsGroup, &cv.Title, &cv.CreatedAt, &cv.UpdatedAt,
+			&msgId, &senderId, &content, &image, &createdAt, &cv.UnreadCount); err != nil {
+			return nil, err
+		}
+
+		if msgId != nil {
+			cv.LastMessage = &dtos.MessageResponse{
+				ID:             *msgId,
+				ConversationID: cv.ID,
+				SenderID:       *senderId,
+				Content:        content,
+				Image:          image,
+				ImageURL:       cr.ImageURL(c, image),
+				CreatedAt:      *createdAt,
+			}
+		}
+		conversations = append(conversations, cv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return conversations, cr.attachMembers(c, conversations)
+}
+
+func (cr *ConversationRepo) GetConversationByID(c context.Context, conversationId int) (*dtos.ConversationResponse, error) {
+	query := `SELECT id, is_group, title, created_at, updated_at FROM conversations WHERE id=$1`
+
+	var cv dtos.ConversationResponse
+	if err := cr.db.QueryRow(c, query, conversationId).Scan(&cv.ID, &cv.IsGroup, &cv.Title, &cv.CreatedAt, &cv.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	conversations := []dtos.ConversationResponse{cv}
+	if err := cr.attachMembers(c, conversations); err != nil {
+		return nil, err
+	}
+	return &conversations[0], nil
+}
+
+// CreateMessage stores the message, bumps the conversation to the top of
+// everyone's list and marks it as read for the sender.
+func (cr *ConversationRepo) CreateMessage(c context.Context, msg *models.Message) error {
+	tx, err := cr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	query := `INSERT INTO messages (conversation_id, sender_id, content, image, created_at)
+	          VALUES ($1, $2, $3, $4, now())
+	          RETURNING id, created_at`
+	if err := tx.QueryRow(c, query, msg.ConversationID, msg.SenderID, msg.Content, msg.Image).Scan(&msg.ID, &msg.CreatedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(c, `UPDATE conversations SET updated_at = now() WHERE id=$1`, msg.ConversationID); err != nil {
+		return err
+	}
+
+	query = `UPDATE conversation_members SET last_read_message_id = $1, last_read_at = now()
+	         WHERE conversation_id=$2 AND user_id=$3`
+	if _, err := tx.Exec(c, query, msg.ID, msg.ConversationID, msg.SenderID); err != nil {
+		return err
+	}
+
+	return tx.Commit(c)
+}
+
+// GetMessages returns up to limit messages older than the before cursor,
+// newest first, and the cursor for the next page when there is one.
+func (cr *ConversationRepo) GetMessages(c context.Context, conversationId int, before *int, limit int) (*dtos.MessageListResponse, error) {
+	query := `SELECT id, conversation_id, sender_id, content, image, created_at
+	          FROM messages
+	          WHERE conversation_id=$1 AND ($2::int IS NULL OR id < $2)
+	          ORDER BY id DESC
+	          LIMIT $3`
+	rows, err := cr.db.Query(c, query, conversationId, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := dtos.MessageListResponse{Messages: []dtos.MessageResponse{}}
+	for rows.Next() {
+		var m dtos.MessageResponse
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.Image, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.ImageURL = cr.ImageURL(c, m.Image)
+		result.Messages = append(result.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(result.Messages) > limit {
+		result.Messages = result.Messages[:limit]
+		cursor := result.Messages[limit-1].ID
+		result.NextCursor = &cursor
+	}
+
+	members, err := cr.getMembers(c, []int{conversationId})
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Messages {
+		result.Messages[i].ReadBy = readBy(result.Messages[i], members[conversationId])
+	}
+
+	return &result, nil
+}
+
+// MarkRead moves the user's read marker up to messageId, or to the latest
+// message when messageId is nil. The marker never moves backwards.
+func (cr *ConversationRepo) MarkRead(c context.Context, conversationId, userId int, messageId *int) (*int, error) {
+	query := `UPDATE conversation_members
+	          SET last_read_message_id = NULLIF(GREATEST(
+	                  COALESCE(last_read_message_id, 0),
+	                  (SELECT max(id) FROM messages WHERE conversation_id = $1 AND ($3::int IS NULL OR id <= $3))
+	              ), 0),
+	              last_read_at = now()
+	          WHERE conversation_id=$1 AND user_id=$2
+	          RETURNING last_read_message_id`
+
+	var lastRead *int
+	err := cr.db.QueryRow(c, query, conversationId, userId, messageId).Scan(&lastRead)
+	return lastRead, err
+}
+
+func (cr *ConversationRepo) attachMembers(c context.Context, conversations []dtos.ConversationResponse) error {
+	ids := make([]int, len(conversations))
+	for i := range conversations {
+		ids[i] = conversations[i].ID
+	}
+
+	members, err := cr.getMembers(c, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range conversations {
+		conversations[i].Members = members[conversations[i].ID]
+		if conversations[i].LastMessage != nil {
+			conversations[i].LastMessage.ReadBy = readBy(*conversations[i].LastMessage, conversations[i].Members)
+		}
+	}
+	return nil
+}
+
+func (cr *ConversationRepo) getMembers(c context.Context, conversationIds []int) (map[int][]dtos.ConversationMemberResponse, error) {
+	members := map[int][]dtos.ConversationMemberResponse{}
+	if len(conversationIds) == 0 {
+		return members, nil
+	}
+
+	query := `SELECT cm.conversation_id, u.id, u.name, u.username, u.avatar, cm.last_read_message_id
+	          FROM conversation_members cm
+	          JOIN users u ON u.id = cm.user_id
+	          WHERE cm.conversation_id = ANY($1)
+	          ORDER BY cm.joined_at, u.id`
+	rows, err := cr.db.Query(c, query, conversationIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			conversationId int
+			m              dtos.ConversationMemberResponse
+		)
+		if err := rows.Scan(&conversationId, &m.UserID, &m.Name, &m.Username, &m.Avatar, &m.LastReadMessageID); err != nil {
+			return nil, err
+		}
+		members[conversationId] = append(members[conversationId], m)
+	}
+	return members, rows.Err()
+}
+
+// readBy lists the members, other than the sender, whose read marker is at or
+// past the message.
+func readBy(m dtos.MessageResponse, members []dtos.ConversationMemberResponse) []int {
+	ids := []int{}
+	for _, member := range members {
+		if member.UserID == m.SenderID || member.LastReadMessageID == nil {
+			continue
+		}
+		if *member.LastReadMessageID >= m.ID {
+			ids = append(ids, member.UserID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,228 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	EventNotification = "notification"
+	EventPost         = "post"
+	EventPostStats    = "post_stats"
+	EventMessage      = "message"
+	EventMessageRead  = "message_read"
+
+	// live counters are broadcast to every instance and never replayed, a
+	// newer value always replaces an older one. Each stream only forwards the
+	// counters of the posts it watches and may see.
+	postStatsChannel = "events:posts"
+
+	// how many past events per user can be resumed with Last-Event-ID
+	eventBacklog    = 500
+	eventBacklogTTL = 24 * time.Hour
+)
+
+type EventRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewEventRepo(db *pgxpool.Pool, rdb *redis.Client) *EventRepo {
+	return &EventRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+func userEventKey(userId int) string {
+	return fmt.Sprintf("events:user:%d", userId)
+}
+
+// publishUserEvent appends the event to the user's backlog stream, so a client
+// can resume after reconnecting, and publishes it to every instance holding a
+// live connection for that user.
+func publishUserEvent(c context.Context, rdb *redis.Client, userId int, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	key := userEventKey(userId)
+	id, err := rdb.XAdd(c, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: eventBacklog,
+		Approx: true,
+		Values: map[string]any{"type": eventType, "data": string(payload)},
+	}).Result()
+	if err != nil {
+		return err
+	}
+	rdb.Expire(c, key, eventBacklogTTL)
+
+	event, err := json.Marshal(dtos.Event{ID: id, Type: eventType, Data: payload})
+	if err != nil {
+		return err
+	}
+	return rdb.Publish(c, key, event).Err()
+}
+
+// PublishToUsers sends the same event to several users. It runs in the
+// background.
+func (er *EventRepo) PublishToUsers(userIds []int, eventType string, data any) {
+	publishInBackground(eventType, func(c context.Context) error {
+		for _, id := range userIds {
+			if err := publishUserEvent(c, er.rdb, id, eventType, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PublishNewPost pushes a freshly created post into the live feed of the
+// followers of its author that are in the post's audience and did not mute
+// the author. Unlisted and only-me posts are not pushed. It runs in the
+// background.
+func (er *EventRepo) PublishNewPost(post dtos.PostResponse) {
+	publishInBackground(EventPost, func(c context.Context) error {
+		return er.publishNewPost(c, post)
+	})
+}
+
+func (er *EventRepo) publishNewPost(c context.Context, post dtos.PostResponse) error {
+	query := fmt.Sprintf(`SELECT f.follower_id FROM follows f
+	          WHERE f.following_id = $1
+	            AND ($2::text IN ('public', 'followers')
+	                 OR ($2::text = 'close_friends' AND EXISTS (
+	                     SELECT 1 FROM close_friends cf WHERE cf.user_id = $1 AND cf.friend_id = f.follower_id)))
+	            AND %s`, notMuted("f.following_id", "f.follower_id"))
+	rows, err := er.db.Query(c, query, post.UserID, post.Visibility)
+	if err != nil {
+		return err
+	}
+
+	followers := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		followers = append(followers, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range followers {
+		if err := publishUserEvent(c, er.rdb, id, EventPost, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishPostStats broadcasts the current like and comment counts of a post.
+// It runs in the background.
+func (er *EventRepo) PublishPostStats(postId int) {
+	publishInBackground(EventPostStats, func(c context.Context) error {
+		return er.publishPostStats(c, postId)
+	})
+}
+
+func (er *EventRepo) publishPostStats(c context.Context, postId int) error {
+	stats := dtos.PostStatsEvent{PostID: postId}
+	query := `SELECT (SELECT count(*) FROM likes WHERE post_id=$1),
+	                 (SELECT count(*) FROM comments WHERE post_id=$1)`
+	if err := er.db.QueryRow(c, query, postId).Scan(&stats.Likes, &stats.Comments); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	event, err := json.Marshal(dtos.Event{Type: EventPostStats, Data: payload})
+	if err != nil {
+		return err
+	}
+	return er.rdb.Publish(c, postStatsChannel, event).Err()
+}
+
+// Subscribe listens to the user's own events and to the live post counters.
+func (er *EventRepo) Subscribe(c context.Context, userId int) (*redis.PubSub, error) {
+	pubsub := er.rdb.Subscribe(c, userEventKey(userId), postStatsChannel)
+	if _, err := pubsub.Receive(c); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
+
+// VisiblePosts returns the ids among postIds of the posts the viewer may see,
+// by the same rules as PostRepo.GetPostByID.
+func (er *EventRepo) VisiblePosts(c context.Context, viewerId int, postIds []int) ([]int, error) {
+	if len(postIds) == 0 {
+		return []int{}, nil
+	}
+
+	query := fmt.Sprintf(`SELECT p.id FROM posts p
+	          WHERE p.id = ANY($1) AND p.deleted_at IS NULL AND %s AND %s AND %s AND %s AND %s AND %s`,
+		notBlocked("p.user_id", "$2"), visibleAccount("p.user_id", "$2"), postAudience("p", "$2", false), notHidden("p", "$2"), published("p", "$2"), notSuspended("p.user_id"))
+	rows, err := er.db.Query(c, query, postIds, viewerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Replay returns the events the user missed after lastEventId.
+func (er *EventRepo) Replay(c context.Context, userId int, lastEventId string) ([]dtos.Event, error) {
+	messages, err := er.rdb.XRange(c, userEventKey(userId), "("+lastEventId, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := []dtos.Event{}
+	for _, m := range messages {
+		eventType, _ := m.Values["type"].(string)
+		data, _ := m.Values["data"].(string)
+		events = append(events, dtos.Event{
+			ID:   m.ID,
+			Type: eventType,
+			Data: json.RawMessage(data),
+		})
+	}
+	return events, nil
+}
+
+// publishInBackground runs a publish without holding up the request that
+// triggered it; realtime delivery is best effort.
+func publishInBackground(name string, publish func(c context.Context) error) {
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := publish(c); err != nil {
+			log.Printf("Failed to publish %s event.\nCause: %s\n", name, err.Error())
+		}
+	}()
+}
@@ -2,17 +2,23 @@ package repos
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
 type FollowRepo struct {
-	db *pgxpool.Pool
+	db  *pgxpool.Pool
+	rdb *redis.Client
 }
 
-func NewFollowRepo(db *pgxpool.Pool) *FollowRepo {
-	return &FollowRepo{db: db}
+func NewFollowRepo(db *pgxpool.Pool, rdb *redis.Client) *FollowRepo {
+	return &FollowRepo{
+		db:  db,
+		rdb: rdb,
+	}
 }
 
 func (fr *FollowRepo) FollowUser(c context.Context, followerId, followingId int) (*dtos.FollowResponse, error) {
@@ -30,6 +36,8 @@ func (fr *FollowRepo) FollowUser(c context.Context, followerId, followingId int)
 		return nil, err
 	}
 
+	// posts of private accounts show up in the follower's feed from now on
+	fr.rdb.Del(c, feedCacheKey(followerId))
 	return &res, nil
 }
 
@@ -39,17 +47,159 @@ func (fr *FollowRepo) UnfollowUser(c context.Context, followerId, followingId in
 	if err != nil {
 		return 0, err
 	}
+
+	fr.rdb.Del(c, feedCacheKey(followerId))
+	return cmdTag.RowsAffected(), nil
+}
+
+func (fr *FollowRepo) IsFollowing(c context.Context, followerId, followingId int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id=$1 AND following_id=$2)`
+	err := fr.db.QueryRow(c, query, followerId, followingId).Scan(&exists)
+	return exists, err
+}
+
+// IsPrivate reports whether the user has a private account. It returns
+// pgx.ErrNoRows when the user does not exist.
+func (fr *FollowRepo) IsPrivate(c context.Context, userId int) (bool, error) {
+	var private bool
+	err := fr.db.QueryRow(c, `SELECT is_private FROM users WHERE id=$1`, userId).Scan(&private)
+	return private, err
+}
+
+// CanViewAccount reports whether viewerId may see the posts and connections of
+// ownerId: always for public accounts, only for the owner and accepted
+// followers of private ones. It returns pgx.ErrNoRows when the owner does not
+// exist.
+func (fr *FollowRepo) CanViewAccount(c context.Context, ownerId, viewerId int) (bool, error) {
+	query := `SELECT NOT u.is_private
+	                 OR u.id = $2
+	                 OR EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $2 AND f.following_id = u.id)
+	          FROM users u
+	          WHERE u.id = $1`
+
+	var visible bool
+	err := fr.db.QueryRow(c, query, ownerId, viewerId).Scan(&visible)
+	return visible, err
+}
+
+// AnyPrivateNotFollowed reports whether any of the users has a private account
+// that followerId does not follow.
+func (fr *FollowRepo) AnyPrivateNotFollowed(c context.Context, followerId int, userIds []int) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM users u
+	              WHERE u.id = ANY($2) AND u.is_private
+	                AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $1 AND f.following_id = u.id)
+	          )`
+
+	var exists bool
+	err := fr.db.QueryRow(c, query, followerId, userIds).Scan(&exists)
+	return exists, err
+}
+
+// CreateFollowRequest asks a private account for permission to follow it. It
+// returns pgx.ErrNoRows when a request is already pending.
+func (fr *FollowRepo) CreateFollowRequest(c context.Context, requesterId, targetId int) (*dtos.FollowRequestResponse, error) {
+	query := `INSERT INTO follow_requests (requester_id, target_id, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (requester_id, target_id) DO NOTHING
+	          RETURNING id, created_at`
+
+	res := dtos.FollowRequestResponse{Requester: dtos.UserResponse{ID: requesterId}}
+	if err := fr.db.QueryRow(c, query, requesterId, targetId).Scan(&res.ID, &res.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CancelFollowRequest withdraws a pending request sent by requesterId.
+func (fr *FollowRepo) CancelFollowRequest(c context.Context, requesterId, targetId int) (int64, error) {
+	query := `DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2`
+	cmdTag, err := fr.db.Exec(c, query, requesterId, targetId)
+	if err != nil {
+		return 0, err
+	}
 	return cmdTag.RowsAffected(), nil
 }
 
-func (fr *FollowRepo) GetFollowers(c context.Context, userId int) ([]dtos.UserResponse, error) {
+// GetFollowRequests lists the pending requests to follow targetId, oldest
+// first.
+func (fr *FollowRepo) GetFollowRequests(c context.Context, targetId int) ([]dtos.FollowRequestResponse, error) {
 	query := `
-		SELECT u.id, u.name, u.email, u.avatar, u.bio, u.created_at, u.updated_at
+		SELECT r.id, r.created_at,
+		       u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
+		FROM follow_requests r
+		JOIN users u ON r.requester_id = u.id
+		WHERE r.target_id = $1
+		ORDER BY r.created_at ASC
+	`
+	rows, err := fr.db.Query(c, query, targetId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []dtos.FollowRequestResponse{}
+	for rows.Next() {
+		var r dtos.FollowRequestResponse
+		u := &r.Requester
+		if err := rows.Scan(&r.ID, &r.CreatedAt, &u.ID, &u.Name, &u.Username, &u.Email, &u.Avatar, &u.Bio, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, rows.Err()
+}
+
+// AcceptFollowRequest turns a pending request addressed to targetId into a
+// follow and returns the requester. It returns pgx.ErrNoRows when there is no
+// such request.
+func (fr *FollowRepo) AcceptFollowRequest(c context.Context, requestId, targetId int) (int, error) {
+	tx, err := fr.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var requesterId int
+	query := `DELETE FROM follow_requests WHERE id = $1 AND target_id = $2 RETURNING requester_id`
+	if err := tx.QueryRow(c, query, requestId, targetId).Scan(&requesterId); err != nil {
+		return 0, err
+	}
+
+	query = `INSERT INTO follows (follower_id, following_id, created_at)
+	         VALUES ($1, $2, now())
+	         ON CONFLICT (follower_id, following_id) DO NOTHING`
+	if _, err := tx.Exec(c, query, requesterId, targetId); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+
+	fr.rdb.Del(c, feedCacheKey(requesterId))
+	return requesterId, nil
+}
+
+// RejectFollowRequest drops a pending request addressed to targetId and
+// returns the requester. It returns pgx.ErrNoRows when there is no such
+// request.
+func (fr *FollowRepo) RejectFollowRequest(c context.Context, requestId, targetId int) (int, error) {
+	var requesterId int
+	query := `DELETE FROM follow_requests WHERE id = $1 AND target_id = $2 RETURNING requester_id`
+	err := fr.db.QueryRow(c, query, requestId, targetId).Scan(&requesterId)
+	return requesterId, err
+}
+
+func (fr *FollowRepo) GetFollowers(c context.Context, userId, viewerId int) ([]dtos.UserResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
 		FROM follows f
 		JOIN users u ON f.follower_id = u.id
-		WHERE f.following_id = $1
-	`
-	rows, err := fr.db.Query(c, query, userId)
+		WHERE f.following_id = $1 AND %s AND %s
+	`, notBlocked("f.follower_id", "$2"), notSuspended("f.follower_id"))
+	rows, err := fr.db.Query(c, query, userId, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +208,7 @@ func (fr *FollowRepo) GetFollowers(c context.Context, userId int) ([]dtos.UserRe
 	var followers []dtos.UserResponse
 	for rows.Next() {
 		var u dtos.UserResponse
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Bio, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Name, &u.Username, &u.Email, &u.Avatar, &u.Bio, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			return nil, err
 		}
 		followers = append(followers, u)
@@ -66,14 +216,14 @@ func (fr *FollowRepo) GetFollowers(c context.Context, userId int) ([]dtos.UserRe
 	return followers, nil
 }
 
-func (fr *FollowRepo) GetFollowing(c context.Context, userId int) ([]dtos.UserResponse, error) {
-	query := `
-		SELECT u.id, u.name, u.email, u.avatar, u.bio, u.created_at, u.updated_at
+func (fr *FollowRepo) GetFollowing(c context.Context, userId, viewerId int) ([]dtos.UserResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
 		FROM follows f
 		JOIN users u ON f.following_id = u.id
-		WHERE f.follower_id = $1
-	`
-	rows, err := fr.db.Query(c, query, userId)
+		WHERE f.follower_id = $1 AND %s AND %s
+	`, notBlocked("f.following_id", "$2"), notSuspended("f.following_id"))
+	rows, err := fr.db.Query(c, query, userId, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +232,7 @@ func (fr *FollowRepo) GetFollowing(c context.Context, userId int) ([]dtos.UserRe
 	var following []dtos.UserResponse
 	for rows.Next() {
 		var u dtos.UserResponse
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Avatar, &u.Bio, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Name, &u.Username, &u.Email, &u.Avatar, &u.Bio, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			return nil, err
 		}
 		following = append(following, u)
@@ -0,0 +1,41 @@
+package repos
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Advisory locks held by the background jobs, so that one app instance at a
+// time runs each of them.
+const (
+	postSchedulerLock = 4604
+	mediaGCLock       = 4605
+	postPurgeLock     = 4606
+)
+
+// ErrJobRunning is returned when a background job is already running on
+// another app instance.
+var ErrJobRunning = errors.New("job is already running on another instance")
+
+// withJobLock runs job while holding the advisory lock, or returns
+// ErrJobRunning without running it. The lock is tied to a transaction kept
+// open for the length of the job, so it is released even if the instance
+// dies halfway.
+func withJobLock(c context.Context, db *pgxpool.Pool, lock int64, job func() error) error {
+	tx, err := db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var locked bool
+	if err := tx.QueryRow(c, `SELECT pg_try_advisory_xact_lock($1)`, lock).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return ErrJobRunning
+	}
+	return job()
+}
@@ -2,6 +2,7 @@ package repos
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
@@ -29,15 +30,15 @@ func (lr *LikeRepo) DeleteLike(c context.Context, userId, postId int) error {
 	return err
 }
 
-func (lr *LikeRepo) GetLikesByPost(c context.Context, postId int) ([]dtos.UserResponse, error) {
-	query := `
-		SELECT u.id, u.name, u.email, u.avatar, u.bio, u.created_at, u.updated_at
+func (lr *LikeRepo) GetLikesByPost(c context.Context, postId, viewerId int) ([]dtos.UserResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT u.id, u.name, u.username, u.email, u.avatar, u.bio, u.is_private, u.created_at, u.updated_at
 		FROM likes l
 		JOIN users u ON l.user_id = u.id
-		WHERE l.post_id = $1
-	`
+		WHERE l.post_id = $1 AND %s AND %s
+	`, notBlocked("l.user_id", "$2"), notSuspended("l.user_id"))
 
-	rows, err := lr.db.Query(c, query, postId)
+	rows, err := lr.db.Query(c, query, postId, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -49,9 +50,11 @@ func (lr *LikeRepo) GetLikesByPost(c context.Context, postId int) ([]dtos.UserRe
 		if err := rows.Scan(
 			&user.ID,
 			&user.Name,
+			&user.Username,
 			&user.Email,
 			&user.Avatar,
 			&user.Bio,
+			&user.IsPrivate,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		); err != nil {
@@ -0,0 +1,122 @@
+package repos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type LinkPreviewRepo struct {
+	db       *pgxpool.Pool
+	rdb      *redis.Client
+	unfurler *utils.Unfurler
+}
+
+func NewLinkPreviewRepo(db *pgxpool.Pool, rdb *redis.Client, unfurler *utils.Unfurler) *LinkPreviewRepo {
+	return &LinkPreviewRepo{
+		db:       db,
+		rdb:      rdb,
+		unfurler: unfurler,
+	}
+}
+
+// unfurlLockKey is held while a URL is being fetched, so that every app
+// instance does not fetch the same page when a link is posted many times.
+func unfurlLockKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "Mosting:unfurl:" + hex.EncodeToString(sum[:])
+}
+
+// UnfurlInBackground fetches the preview of a link without holding up the
+// request that posted it.
+func (lr *LinkPreviewRepo) UnfurlInBackground(url string) {
+	go func() {
+		c, cancel := context.WithTimeout(context.Background(), 2*utils.UnfurlTimeout)
+		defer cancel()
+		if err := lr.Unfurl(c, url); err != nil {
+			log.Printf("Failed to unfurl %s.\nCause: %s\n", url, err.Error())
+		}
+	}()
+}
+
+// Unfurl fetches and stores the preview of a link unless it was fetched
+// recently or is being fetched already. A page without a preview is stored as
+// failed and tried again after models.LinkPreviewRetry.
+func (lr *LinkPreviewRepo) Unfurl(c context.Context, url string) error {
+	var fresh bool
+	query := `SELECT fetched_at > now() - make_interval(secs => CASE WHEN status = 'ok' THEN $2 ELSE $3 END)
+	          FROM link_previews WHERE url=$1`
+	err := lr.db.QueryRow(c, query, url, models.LinkPreviewTTL.Seconds(), models.LinkPreviewRetry.Seconds()).Scan(&fresh)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if fresh {
+		return nil
+	}
+
+	locked, err := lr.rdb.SetNX(c, unfurlLockKey(url), 1, 2*utils.UnfurlTimeout).Result()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer lr.rdb.Del(context.Background(), unfurlLockKey(url))
+
+	preview, fetchErr := lr.unfurler.Unfurl(c, url)
+	if fetchErr != nil {
+		preview = &models.LinkPreview{URL: url, Status: models.LinkPreviewFailed}
+	} else {
+		preview.Status = models.LinkPreviewOK
+	}
+
+	query = `INSERT INTO link_previews (url, status, title, description, image_url, site_name, fetched_at)
+	         VALUES ($1, $2, $3, $4, $5, $6, now())
+	         ON CONFLICT (url) DO UPDATE
+	         SET status = EXCLUDED.status, title = EXCLUDED.title, description = EXCLUDED.description,
+	             image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name, fetched_at = EXCLUDED.fetched_at`
+	if _, err := lr.db.Exec(c, query, preview.URL, preview.Status, preview.Title, preview.Description, preview.ImageURL, preview.SiteName); err != nil {
+		return err
+	}
+	return fetchErr
+}
+
+// loadLinkPreviews returns the previews of the links of the given posts keyed
+// by post id. Posts whose link has no preview (yet) are left out.
+func loadLinkPreviews(c context.Context, db *pgxpool.Pool, postIds []int) (map[int]*dtos.LinkPreviewResponse, error) {
+	result := map[int]*dtos.LinkPreviewResponse{}
+	if len(postIds) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT p.id, lp.url, lp.title, lp.description, lp.image_url, lp.site_name
+	          FROM posts p
+	          JOIN link_previews lp ON lp.url = p.link_url AND lp.status = 'ok'
+	          WHERE p.id = ANY($1)`
+	rows, err := db.Query(c, query, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postId int
+			lp     dtos.LinkPreviewResponse
+		)
+		if err := rows.Scan(&postId, &lp.URL, &lp.Title, &lp.Description, &lp.ImageURL, &lp.SiteName); err != nil {
+			return nil, err
+		}
+		result[postId] = &lp
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,242 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MediaRepo struct {
+	db    *pgxpool.Pool
+	store storage.Storage
+}
+
+func NewMediaRepo(db *pgxpool.Pool, store storage.Storage) *MediaRepo {
+	return &MediaRepo{
+		db:    db,
+		store: store,
+	}
+}
+
+// FindUpload returns the stored sizes of an upload and marks them used so the
+// collector leaves them alone for another grace period. It returns nil when
+// the upload is not stored.
+func (mr *MediaRepo) FindUpload(c context.Context, upload string) (models.ImageVariants, error) {
+	query := `UPDATE media_files SET last_used_at = now()
+	          WHERE upload = $1
+	          RETURNING key, variant, width, height`
+	rows, err := mr.db.Query(c, query, upload)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants models.ImageVariants
+	for rows.Next() {
+		var (
+			variant string
+			v       models.ImageVariant
+		)
+		if err := rows.Scan(&v.Filename, &variant, &v.Width, &v.Height); err != nil {
+			return nil, err
+		}
+		if variants == nil {
+			variants = models.ImageVariants{}
+		}
+		variants[variant] = v
+	}
+	return variants, rows.Err()
+}
+
+func (mr *MediaRepo) RegisterFiles(c context.Context, files []models.MediaFile) error {
+	query := `INSERT INTO media_files (key, upload, variant, width, height, size_bytes, content_type, created_at, last_used_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+	          ON CONFLICT (key) DO UPDATE SET last_used_at = now()`
+	for _, f := range files {
+		if _, err := mr.db.Exec(c, query, f.Key, f.Upload, f.Variant, f.Width, f.Height, f.SizeBytes, f.ContentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unreferencedUploads are the uploads no post, past revision of a post, avatar
+// or message points at, and that are not waiting to be put into a post. Posts
+// and messages refer to the full size, which brings every other size of the
+// same upload along.
+const unreferencedUploads = `
+	SELECT mf.key FROM media_files mf
+	WHERE mf.last_used_at < now() - make_interval(secs => $1)
+	  AND NOT EXISTS (
+	    SELECT 1 FROM media_files ref
+	    WHERE ref.upload = mf.upload
+	      AND (EXISTS (SELECT 1 FROM post_media pm WHERE pm.filename = ref.key)
+	           OR EXISTS (SELECT 1 FROM post_revision_media prm WHERE prm.filename = ref.key)
+	           OR EXISTS (SELECT 1 FROM users u WHERE u.avatar = ref.key)
+	           OR EXISTS (SELECT 1 FROM messages m WHERE m.image = ref.key)
+	           OR EXISTS (SELECT 1 FROM media_uploads mu
+	                      WHERE mu.filename = ref.key AND mu.status = 'pending' AND mu.expires_at > now())))`
+
+// CollectGarbage deletes the files of uploads nothing refers to that were not
+// used for longer than grace, which covers uploads whose post was never
+// saved and images replaced by newer ones. Expired media uploads go as well.
+// With dryRun it only returns what it would delete. It returns ErrJobRunning
+// when another instance is collecting.
+func (mr *MediaRepo) CollectGarbage(c context.Context, grace time.Duration, dryRun bool) ([]string, error) {
+	if dryRun {
+		return mr.collectGarbage(c, grace, true)
+	}
+
+	var deleted []string
+	err := withJobLock(c, mr.db, mediaGCLock, func() error {
+		var err error
+		deleted, err = mr.collectGarbage(c, grace, false)
+		return err
+	})
+	return deleted, err
+}
+
+func (mr *MediaRepo) collectGarbage(c context.Context, grace time.Duration, dryRun bool) ([]string, error) {
+	rows, err := mr.db.Query(c, unreferencedUploads+` ORDER BY mf.key`, grace.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return keys, nil
+	}
+
+	// uploads that were never finished or put into a post
+	if _, err := mr.db.Exec(c, `DELETE FROM media_uploads WHERE status <> 'attached' AND expires_at < now()`); err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for _, key := range keys {
+		// checked again in case the upload was reused since the listing
+		tag, err := mr.db.Exec(c, `DELETE FROM media_files WHERE key IN (`+unreferencedUploads+`) AND key = $2`, grace.Seconds(), key)
+		if err != nil {
+			return deleted, err
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		if err := mr.store.Delete(c, key); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, key)
+	}
+	return deleted, nil
+}
+
+// StartGC collects garbage every interval in the background. Every app
+// instance can run it, one at a time gets to collect.
+func (mr *MediaRepo) StartGC(interval, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c, cancel := context.WithTimeout(context.Background(), interval)
+			deleted, err := mr.CollectGarbage(c, grace, false)
+			cancel()
+			if err != nil && !errors.Is(err, ErrJobRunning) {
+				log.Println("Media garbage collection failed:", err)
+			}
+			if len(deleted) > 0 {
+				log.Printf("Media garbage collection deleted %d files\n", len(deleted))
+			}
+		}
+	}()
+}
+
+// heldUploads are the uploads a user's posts and their revisions, avatar,
+// messages and pending media refer to, by what they are used for.
+const heldUploads = `
+	WITH held AS (
+	  SELECT 'posts' AS kind, pm.filename AS key FROM post_media pm JOIN posts p ON p.id = pm.post_id WHERE p.user_id = $1
+	  UNION ALL SELECT 'posts', prm.filename FROM post_revision_media prm
+	            JOIN post_revisions pr ON pr.id = prm.revision_id JOIN posts p ON p.id = pr.post_id
+	            WHERE p.user_id = $1
+	  UNION ALL SELECT 'avatar', avatar FROM users WHERE id = $1 AND avatar IS NOT NULL
+	  UNION ALL SELECT 'messages', image FROM messages WHERE sender_id = $1 AND image IS NOT NULL
+	  UNION ALL SELECT 'pending', filename FROM media_uploads
+	            WHERE user_id = $1 AND status = 'pending' AND expires_at > now()
+	), uploads AS (
+	  SELECT DISTINCT h.kind, f.upload FROM held h JOIN media_files f ON f.key = h.key
+	)`
+
+// GetStorageUsage adds up the stored sizes of every upload the user's media
+// refers to, along with the user's own quota if there is one. Resumable
+// uploads still coming in count as pending with the size they announced.
+func (mr *MediaRepo) GetStorageUsage(c context.Context, userId int) (*models.StorageUsage, error) {
+	var usage models.StorageUsage
+	if err := mr.db.QueryRow(c, `SELECT storage_quota FROM users WHERE id = $1`, userId).Scan(&usage.Quota); err != nil {
+		return nil, err
+	}
+
+	query := heldUploads + `
+	SELECT u.kind, COALESCE(SUM(mf.size_bytes), 0)
+	FROM uploads u JOIN media_files mf ON mf.upload = u.upload
+	GROUP BY u.kind`
+	rows, err := mr.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			kind string
+			size int64
+		)
+		if err := rows.Scan(&kind, &size); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "posts":
+			usage.Posts = size
+		case "avatar":
+			usage.Avatar = size
+		case "messages":
+			usage.Messages = size
+		case "pending":
+			usage.Pending = size
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = heldUploads + `
+	SELECT COALESCE(SUM(size_bytes), 0) FROM media_files
+	WHERE upload IN (SELECT upload FROM uploads)`
+	if err := mr.db.QueryRow(c, query, userId).Scan(&usage.Total); err != nil {
+		return nil, err
+	}
+
+	var uploading int64
+	query = `SELECT COALESCE(SUM(size_bytes), 0) FROM media_uploads
+	         WHERE user_id = $1 AND status = 'uploading' AND expires_at > now()`
+	if err := mr.db.QueryRow(c, query, userId).Scan(&uploading); err != nil {
+		return nil, err
+	}
+	usage.Pending += uploading
+	usage.Total += uploading
+	return &usage, nil
+}
@@ -0,0 +1,200 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MentionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMentionRepo(db *pgxpool.Pool) *MentionRepo {
+	return &MentionRepo{db: db}
+}
+
+// SaveMentions replaces the mentions stored for a post (or for one of its
+// comments when commentId is set) with the ones found in text. It returns the
+// resolved mentions and the ids of users that were not mentioned there before,
+// so callers only notify people once per post or comment.
+func (mr *MentionRepo) SaveMentions(c context.Context, postId int, commentId *int, authorId int, text string) ([]dtos.MentionResponse, []int, error) {
+	tokens := utils.ParseMentions(text)
+
+	handles := []string{}
+	for _, t := range tokens {
+		handles = append(handles, strings.ToLower(t.Username))
+	}
+
+	users := map[string]dtos.MentionResponse{}
+	if len(handles) > 0 {
+		// users in a block with the author cannot be mentioned, their handle
+		// stays plain text
+		query := fmt.Sprintf(`SELECT id, username FROM users WHERE lower(username) = ANY($1) AND %s`, notBlocked("id", "$2"))
+		rows, err := mr.db.Query(c, query, handles, authorId)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			var u dtos.MentionResponse
+			if err := rows.Scan(&u.UserID, &u.Username); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			users[strings.ToLower(u.Username)] = u
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tx, err := mr.db.Begin(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(c)
+
+	previous := map[int]bool{}
+	rows, err := tx.Query(c, `DELETE FROM mentions
+	          WHERE post_id=$1 AND comment_id IS NOT DISTINCT FROM $2
+	          RETURNING mentioned_user_id`, postId, commentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		previous[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	mentions := []dtos.MentionResponse{}
+	newlyMentioned := []int{}
+	seen := map[int]bool{}
+	for _, t := range tokens {
+		u, ok := users[strings.ToLower(t.Username)]
+		if !ok {
+			continue
+		}
+
+		query := `INSERT INTO mentions (post_id, comment_id, author_id, mentioned_user_id, start_offset, length, created_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, now())`
+		if _, err := tx.Exec(c, query, postId, commentId, authorId, u.UserID, t.Offset, t.Length); err != nil {
+			return nil, nil, err
+		}
+
+		mentions = append(mentions, dtos.MentionResponse{
+			UserID:   u.UserID,
+			Username: u.Username,
+			Offset:   t.Offset,
+			Length:   t.Length,
+		})
+
+		if !previous[u.UserID] && !seen[u.UserID] && u.UserID != authorId {
+			newlyMentioned = append(newlyMentioned, u.UserID)
+		}
+		seen[u.UserID] = true
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, nil, err
+	}
+
+	return mentions, newlyMentioned, nil
+}
+
+func (mr *MentionRepo) GetMentionsOfUser(c context.Context, userId, limit, offset int) ([]dtos.MentionFeedResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT id, post_id, comment_id, author_id, author_name, author_username, content, created_at
+		FROM (
+			SELECT DISTINCT ON (m.post_id, m.comment_id)
+			       m.id, m.post_id, m.comment_id, m.author_id, u.name AS author_name, u.username AS author_username,
+			       COALESCE(cm.content, p.content_text, '') AS content, m.created_at
+			FROM mentions m
+			JOIN posts p ON p.id = m.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+			LEFT JOIN comments cm ON cm.id = m.comment_id
+			JOIN users u ON u.id = m.author_id
+			WHERE m.mentioned_user_id = $1
+			  AND (m.comment_id IS NULL OR cm.id IS NOT NULL)
+			  AND (cm.id IS NULL OR cm.hidden_at IS NULL)
+			  AND %s AND %s AND %s AND %s AND %s AND %s
+			ORDER BY m.post_id, m.comment_id, m.start_offset
+		) feed
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, notBlocked("m.author_id", "$1"), notMuted("m.author_id", "$1"), postAudience("p", "$1", false), notHidden("p", "$1"), notSuspended("m.author_id"),
+		noMutedWords("COALESCE(cm.content, p.content_text)", "$1"))
+	rows, err := mr.db.Query(c, query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []dtos.MentionFeedResponse{}
+	for rows.Next() {
+		var m dtos.MentionFeedResponse
+		if err := rows.Scan(&m.ID, &m.PostID, &m.CommentID, &m.AuthorID, &m.AuthorName, &m.AuthorUsername, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, rows.Err()
+}
+
+// loadMentions returns the mentions of the given posts keyed by post id, or of
+// the given comments keyed by comment id when forComments is true.
+func loadMentions(c context.Context, db *pgxpool.Pool, ids []int, forComments bool) (map[int][]dtos.MentionResponse, error) {
+	result := map[int][]dtos.MentionResponse{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	filter := "m.post_id = ANY($1) AND m.comment_id IS NULL"
+	if forComments {
+		filter = "m.comment_id = ANY($1)"
+	}
+
+	query := `SELECT m.post_id, m.comment_id, m.mentioned_user_id, u.username, m.start_offset, m.length
+	          FROM mentions m
+	          JOIN users u ON u.id = m.mentioned_user_id
+	          WHERE ` + filter + `
+	          ORDER BY m.start_offset`
+	rows, err := db.Query(c, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postId    int
+			commentId *int
+			username  *string
+			m         dtos.MentionResponse
+		)
+		if err := rows.Scan(&postId, &commentId, &m.UserID, &username, &m.Offset, &m.Length); err != nil {
+			return nil, err
+		}
+		if username != nil {
+			m.Username = *username
+		}
+
+		key := postId
+		if forComments {
+			key = *commentId
+		}
+		result[key] = append(result[key], m)
+	}
+	return result, rows.Err()
+}
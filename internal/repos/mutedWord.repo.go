@@ -0,0 +1,70 @@
+package repos
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+type MutedWordRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewMutedWordRepo(db *pgxpool.Pool, rdb *redis.Client) *MutedWordRepo {
+	return &MutedWordRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// MuteWord hides posts containing the word from the user's feeds. Words are
+// matched whole and case-insensitively, and stored lowercase. It returns
+// pgx.ErrNoRows when the word is already muted.
+func (mr *MutedWordRepo) MuteWord(c context.Context, mw *models.MutedWord) error {
+	mw.Word = strings.ToLower(strings.TrimSpace(mw.Word))
+
+	query := `INSERT INTO muted_words (user_id, word, created_at)
+	          VALUES ($1, $2, now())
+	          ON CONFLICT (user_id, word) DO NOTHING
+	          RETURNING id, created_at`
+	if err := mr.db.QueryRow(c, query, mw.UserID, mw.Word).Scan(&mw.ID, &mw.CreatedAt); err != nil {
+		return err
+	}
+
+	mr.rdb.Del(c, feedCacheKey(mw.UserID))
+	return nil
+}
+
+func (mr *MutedWordRepo) UnmuteWord(c context.Context, userId, wordId int) (int64, error) {
+	cmdTag, err := mr.db.Exec(c, `DELETE FROM muted_words WHERE id=$1 AND user_id=$2`, wordId, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	mr.rdb.Del(c, feedCacheKey(userId))
+	return cmdTag.RowsAffected(), nil
+}
+
+func (mr *MutedWordRepo) GetMutedWords(c context.Context, userId int) ([]dtos.MutedWordResponse, error) {
+	query := `SELECT id, word, created_at FROM muted_words WHERE user_id=$1 ORDER BY word`
+	rows, err := mr.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := []dtos.MutedWordResponse{}
+	for rows.Next() {
+		var w dtos.MutedWordResponse
+		if err := rows.Scan(&w.ID, &w.Word, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
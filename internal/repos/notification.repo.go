@@ -0,0 +1,373 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Notifications of the same type about the same post on the same day are
+// shown as a single entry, e.g. "Alice and 4 others liked your post".
+const notificationGroup = "n.type, n.post_id, date_trunc('day', n.created_at)"
+
+// moderation notifications get through blocks and mutes
+const systemNotificationTypes = "'warning', 'report_resolved'"
+
+// notifications about posts that were deleted afterwards, or caused by users
+// the recipient blocked or muted since, are not shown
+var notificationVisible = "NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = n.post_id AND p.deleted_at IS NOT NULL)" +
+	" AND (n.type IN (" + systemNotificationTypes + ")" +
+	" OR (" + notBlocked("n.actor_id", "n.user_id") + " AND " + notMuted("n.actor_id", "n.user_id") + "))"
+
+const maxActorsShown = 3
+
+type NotificationRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewNotificationRepo(db *pgxpool.Pool, rdb *redis.Client) *NotificationRepo {
+	return &NotificationRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+func (nr *NotificationRepo) CreateNotification(c context.Context, n *models.Notification) error {
+	// nobody needs to be told about their own actions
+	if n.UserID == n.ActorID {
+		return nil
+	}
+
+	query := `INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, created_at)
+	          SELECT $1, $2, $3, $4, $5, now()
+	          WHERE NOT EXISTS (
+	              SELECT 1 FROM notification_preferences
+	              WHERE user_id = $1 AND type = $3 AND enabled = false
+	          ) AND ($3 IN (%s) OR (%s AND %s))
+	          RETURNING id, created_at`
+	query = fmt.Sprintf(query, systemNotificationTypes, notBlocked("$2::int", "$1"), notMuted("$2::int", "$1"))
+	err := nr.db.QueryRow(c, query, n.UserID, n.ActorID, n.Type, n.PostID, n.CommentID).Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// the recipient turned this category off, or blocked or muted the actor
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	event := dtos.NotificationEvent{
+		ID:        n.ID,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+		PostID:    n.PostID,
+		CommentID: n.CommentID,
+		CreatedAt: n.CreatedAt,
+	}
+	if isSystemNotification(n.Type) {
+		event.ActorID = 0
+	}
+	if err := publishUserEvent(c, nr.rdb, n.UserID, EventNotification, event); err != nil {
+		log.Println("Failed to publish notification event.\nCause:", err.Error())
+	}
+	return nil
+}
+
+// NotifyFollowers tells the followers of a post's author that the post was
+// published. Like the live feed, only followers in the post's audience are
+// told, so unlisted and only-me posts notify nobody. Followers who blocked or
+// muted the author, or switched these notifications off, are left out.
+func (nr *NotificationRepo) NotifyFollowers(c context.Context, authorId, postId int, visibility string) error {
+	query := fmt.Sprintf(`INSERT INTO notifications (user_id, actor_id, type, post_id, created_at)
+	          SELECT f.follower_id, $1, $3, $2, now()
+	          FROM follows f
+	          WHERE f.following_id = $1
+	            AND ($4::text IN ('public', 'followers')
+	                 OR ($4::text = 'close_friends' AND EXISTS (
+	                     SELECT 1 FROM close_friends cf WHERE cf.user_id = $1 AND cf.friend_id = f.follower_id)))
+	            AND NOT EXISTS (
+	                SELECT 1 FROM notification_preferences np
+	                WHERE np.user_id = f.follower_id AND np.type = $3 AND np.enabled = false
+	            ) AND %s AND %s
+	          RETURNING id, user_id, created_at`, notBlocked("$1::int", "f.follower_id"), notMuted("$1::int", "f.follower_id"))
+	rows, err := nr.db.Query(c, query, authorId, postId, models.NotificationPost, visibility)
+	if err != nil {
+		return err
+	}
+
+	type recipient struct {
+		userId int
+		event  dtos.NotificationEvent
+	}
+	recipients := []recipient{}
+	for rows.Next() {
+		r := recipient{event: dtos.NotificationEvent{Type: models.NotificationPost, ActorID: authorId, PostID: &postId}}
+		if err := rows.Scan(&r.event.ID, &r.userId, &r.event.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range recipients {
+		if err := publishUserEvent(c, nr.rdb, r.userId, EventNotification, r.event); err != nil {
+			log.Println("Failed to publish notification event.\nCause:", err.Error())
+		}
+	}
+	return nil
+}
+
+// DeleteNotification removes a notification whose cause was undone, such as an
+// unlike or an unfollow.
+func (nr *NotificationRepo) DeleteNotification(c context.Context, userId, actorId int, notifType string, postId *int) error {
+	query := `DELETE FROM notifications
+	          WHERE user_id=$1 AND actor_id=$2 AND type=$3 AND post_id IS NOT DISTINCT FROM $4`
+	_, err := nr.db.Exec(c, query, userId, actorId, notifType, postId)
+	return err
+}
+
+func (nr *NotificationRepo) GetNotifications(c context.Context, userId, limit, offset int) ([]dtos.NotificationResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT max(n.id), n.type, n.post_id,
+		       array_agg(n.actor_id ORDER BY n.created_at DESC),
+		       count(DISTINCT n.actor_id),
+		       bool_and(n.read_at IS NOT NULL),
+		       max(n.created_at) AS latest
+		FROM notifications n
+		WHERE n.user_id = $1 AND %s
+		GROUP BY %s
+		ORDER BY latest DESC
+		LIMIT $2 OFFSET $3
+	`, notificationVisible, notificationGroup)
+
+	rows, err := nr.db.Query(c, query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []dtos.NotificationResponse{}
+	groupActors := [][]int{}
+	actorIds := []int{}
+	for rows.Next() {
+		var (
+			n      dtos.NotificationResponse
+			actors []int
+		)
+		if err := rows.Scan(&n.ID, &n.Type, &n.PostID, &actors, &n.ActorCount, &n.Read, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		// most recent actors first, each only once
+		seen := map[int]bool{}
+		latest := []int{}
+		for _, id := range actors {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			latest = append(latest, id)
+			actorIds = append(actorIds, id)
+			if len(latest) == maxActorsShown {
+				break
+			}
+		}
+
+		notifications = append(notifications, n)
+		groupActors = append(groupActors, latest)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	actors, err := nr.getActors(c, actorIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notifications {
+		notifications[i].Actors = []dtos.NotificationActor{}
+		for _, id := range groupActors[i] {
+			if a, ok := actors[id]; ok {
+				notifications[i].Actors = append(notifications[i].Actors, a)
+			}
+		}
+		if isSystemNotification(notifications[i].Type) {
+			notifications[i].Actors = []dtos.NotificationActor{}
+		}
+		notifications[i].Message = notificationMessage(notifications[i])
+	}
+
+	return notifications, nil
+}
+
+func (nr *NotificationRepo) CountUnread(c context.Context, userId int) (int, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) FROM (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = $1 AND n.read_at IS NULL AND %s
+			GROUP BY %s
+		) unread
+	`, notificationVisible, notificationGroup)
+
+	var count int
+	err := nr.db.QueryRow(c, query, userId).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks the group that notification belongs to as read. It returns
+// pgx.ErrNoRows when the notification does not belong to the user.
+func (nr *NotificationRepo) MarkRead(c context.Context, userId, notificationId int) error {
+	var (
+		notifType string
+		postId    *int
+		day       time.Time
+	)
+	query := `SELECT type, post_id, date_trunc('day', created_at) FROM notifications WHERE id=$1 AND user_id=$2`
+	if err := nr.db.QueryRow(c, query, notificationId, userId).Scan(&notifType, &postId, &day); err != nil {
+		return err
+	}
+
+	query = `UPDATE notifications SET read_at = now()
+	         WHERE user_id=$1 AND read_at IS NULL
+	           AND type=$2 AND post_id IS NOT DISTINCT FROM $3
+	           AND date_trunc('day', created_at) = $4`
+	_, err := nr.db.Exec(c, query, userId, notifType, postId, day)
+	return err
+}
+
+func (nr *NotificationRepo) MarkAllRead(c context.Context, userId int) error {
+	query := `UPDATE notifications SET read_at = now() WHERE user_id=$1 AND read_at IS NULL`
+	_, err := nr.db.Exec(c, query, userId)
+	return err
+}
+
+func (nr *NotificationRepo) GetPreferences(c context.Context, userId int) (dtos.NotificationPreferences, error) {
+	prefs := dtos.NotificationPreferences{}
+	for _, t := range models.NotificationTypes {
+		prefs[t] = true
+	}
+
+	rows, err := nr.db.Query(c, `SELECT type, enabled FROM notification_preferences WHERE user_id=$1`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			t       string
+			enabled bool
+		)
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		if _, ok := prefs[t]; ok {
+			prefs[t] = enabled
+		}
+	}
+	return prefs, rows.Err()
+}
+
+func (nr *NotificationRepo) UpdatePreferences(c context.Context, userId int, prefs dtos.NotificationPreferences) error {
+	tx, err := nr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	query := `INSERT INTO notification_preferences (user_id, type, enabled, updated_at)
+	          VALUES ($1, $2, $3, now())
+	          ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = now()`
+	for t, enabled := range prefs {
+		if _, err := tx.Exec(c, query, userId, t, enabled); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+func (nr *NotificationRepo) getActors(c context.Context, ids []int) (map[int]dtos.NotificationActor, error) {
+	actors := map[int]dtos.NotificationActor{}
+	if len(ids) == 0 {
+		return actors, nil
+	}
+
+	rows, err := nr.db.Query(c, `SELECT id, name, username, avatar FROM users WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a dtos.NotificationActor
+		if err := rows.Scan(&a.ID, &a.Name, &a.Username, &a.Avatar); err != nil {
+			return nil, err
+		}
+		actors[a.ID] = a
+	}
+	return actors, rows.Err()
+}
+
+func isSystemNotification(t string) bool {
+	return t == models.NotificationWarning || t == models.NotificationReportResolved
+}
+
+func notificationMessage(n dtos.NotificationResponse) string {
+	switch n.Type {
+	case models.NotificationWarning:
+		return "Your content goes against our community guidelines"
+	case models.NotificationReportResolved:
+		return "A report you sent has been reviewed"
+	}
+
+	who := "Someone"
+	if len(n.Actors) > 0 {
+		a := n.Actors[0]
+		switch {
+		case a.Name != nil && *a.Name != "":
+			who = *a.Name
+		case a.Username != nil && *a.Username != "":
+			who = "@" + *a.Username
+		}
+	}
+
+	switch others := n.ActorCount - 1; {
+	case others == 1:
+		who += " and 1 other"
+	case others > 1:
+		who += fmt.Sprintf(" and %d others", others)
+	}
+
+	switch n.Type {
+	case models.NotificationLike:
+		return who + " liked your post"
+	case models.NotificationComment:
+		return who + " commented on your post"
+	case models.NotificationFollow:
+		return who + " started following you"
+	case models.NotificationMention:
+		return who + " mentioned you"
+	case models.NotificationFollowRequest:
+		return who + " requested to follow you"
+	case models.NotificationFollowAccept:
+		return who + " accepted your follow request"
+	case models.NotificationPost:
+		return who + " shared a new post"
+	default:
+		return who + " interacted with you"
+	}
+}
@@ -0,0 +1,223 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Errors of a vote that cannot be counted.
+var (
+	ErrPollClosed     = errors.New("poll is closed")
+	ErrAlreadyVoted   = errors.New("already voted in this poll")
+	ErrInvalidChoices = errors.New("invalid poll choices")
+)
+
+// ErrPollWindow is returned when a post would be published while its poll is
+// closed, or with its poll open longer than models.MaxPollDuration.
+var ErrPollWindow = errors.New("poll does not fit the publish time")
+
+// pollOutsideWindow matches posts whose poll would be closed when the post is
+// published at publishAt, a SQL expression, or would stay open longer than
+// models.MaxPollDuration after it. Posts without a poll never match.
+func pollOutsideWindow(postAlias, publishAt string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM polls pw
+		WHERE pw.post_id = %[1]s.id
+		  AND (pw.expires_at <= %[2]s OR pw.expires_at > %[2]s + make_interval(secs => %[3]d))
+	)`, postAlias, publishAt, int(models.MaxPollDuration.Seconds()))
+}
+
+type PollRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewPollRepo(db *pgxpool.Pool, rdb *redis.Client) *PollRepo {
+	return &PollRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// insertPoll stores the poll of a post with its options in order.
+func insertPoll(c context.Context, tx pgx.Tx, poll *models.Poll) error {
+	query := `INSERT INTO polls (post_id, multiple, expires_at, created_at)
+	          VALUES ($1, $2, $3::timestamptz, now())
+	          RETURNING id, expires_at, created_at`
+	if err := tx.QueryRow(c, query, poll.PostID, poll.Multiple, poll.ExpiresAt).Scan(&poll.ID, &poll.ExpiresAt, &poll.CreatedAt); err != nil {
+		return err
+	}
+
+	for i := range poll.Options {
+		poll.Options[i].PollID = poll.ID
+		poll.Options[i].Position = i
+		query := `INSERT INTO poll_options (poll_id, position, text) VALUES ($1, $2, $3) RETURNING id`
+		if err := tx.QueryRow(c, query, poll.ID, i, poll.Options[i].Text).Scan(&poll.Options[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Vote counts the one vote of a user in the poll of a post. Choices must be
+// options of the poll, and a single one unless the poll allows multiple
+// choices. It returns pgx.ErrNoRows when the post has no poll, ErrPollClosed,
+// ErrAlreadyVoted or ErrInvalidChoices. Tallies are kept by the same
+// transaction that stores the vote, so they always match the votes.
+func (pr *PollRepo) Vote(c context.Context, postId, userId int, optionIds []int) error {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var (
+		pollId   int
+		multiple bool
+		closed   bool
+	)
+	query := `SELECT id, multiple, expires_at <= now() FROM polls WHERE post_id=$1`
+	if err := tx.QueryRow(c, query, postId).Scan(&pollId, &multiple, &closed); err != nil {
+		return err
+	}
+	if closed {
+		return ErrPollClosed
+	}
+
+	rows, err := tx.Query(c, `SELECT id FROM poll_options WHERE poll_id=$1`, pollId)
+	if err != nil {
+		return err
+	}
+	options := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		options = append(options, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(optionIds) == 0 || (!multiple && len(optionIds) > 1) {
+		return ErrInvalidChoices
+	}
+	for i, id := range optionIds {
+		if !slices.Contains(options, id) || slices.Contains(optionIds[:i], id) {
+			return ErrInvalidChoices
+		}
+	}
+
+	var voteId int
+	query = `INSERT INTO poll_votes (poll_id, user_id, option_ids, created_at)
+	         VALUES ($1, $2, $3, now())
+	         ON CONFLICT (poll_id, user_id) DO NOTHING
+	         RETURNING id`
+	if err := tx.QueryRow(c, query, pollId, userId, optionIds).Scan(&voteId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAlreadyVoted
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(c, `UPDATE poll_options SET votes_count = votes_count + 1 WHERE poll_id=$1 AND id = ANY($2)`, pollId, optionIds); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(c, `UPDATE polls SET voters_count = voters_count + 1 WHERE id=$1`, pollId); err != nil {
+		return err
+	}
+	if err := tx.Commit(c); err != nil {
+		return err
+	}
+
+	pr.rdb.Del(c, feedCacheKey(userId))
+	return nil
+}
+
+// loadPolls returns the polls of the given posts keyed by post id, as seen by
+// viewerId: tallies are only filled in once the viewer voted or the poll
+// closed.
+func loadPolls(c context.Context, db *pgxpool.Pool, postIds []int, viewerId int) (map[int]*dtos.PollResponse, error) {
+	result := map[int]*dtos.PollResponse{}
+	if len(postIds) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT pl.id, pl.post_id, pl.multiple, pl.voters_count, pl.expires_at, pl.expires_at <= now(), pv.option_ids
+	          FROM polls pl
+	          LEFT JOIN poll_votes pv ON pv.poll_id = pl.id AND pv.user_id = $2
+	          WHERE pl.post_id = ANY($1)`
+	rows, err := db.Query(c, query, postIds, viewerId)
+	if err != nil {
+		return nil, err
+	}
+	byPoll := map[int]*dtos.PollResponse{}
+	pollIds := []int{}
+	for rows.Next() {
+		var (
+			postId      int
+			votersCount int
+			choices     []int
+			p           dtos.PollResponse
+		)
+		if err := rows.Scan(&p.ID, &postId, &p.Multiple, &votersCount, &p.ExpiresAt, &p.Closed, &choices); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Voted = choices != nil
+		p.MyChoices = choices
+		if p.MyChoices == nil {
+			p.MyChoices = []int{}
+		}
+		if p.Voted || p.Closed {
+			p.VotersCount = &votersCount
+		}
+		p.Options = []dtos.PollOptionResponse{}
+		result[postId] = &p
+		byPoll[p.ID] = &p
+		pollIds = append(pollIds, p.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(pollIds) == 0 {
+		return result, nil
+	}
+
+	query = `SELECT id, poll_id, text, votes_count FROM poll_options
+	         WHERE poll_id = ANY($1)
+	         ORDER BY poll_id, position`
+	optionRows, err := db.Query(c, query, pollIds)
+	if err != nil {
+		return nil, err
+	}
+	defer optionRows.Close()
+	for optionRows.Next() {
+		var (
+			pollId int
+			votes  int
+			o      dtos.PollOptionResponse
+		)
+		if err := optionRows.Scan(&o.ID, &pollId, &o.Text, &votes); err != nil {
+			return nil, err
+		}
+		p := byPoll[pollId]
+		if p.Voted || p.Closed {
+			o.Votes = &votes
+		}
+		p.Options = append(p.Options, o)
+	}
+	return result, optionRows.Err()
+}
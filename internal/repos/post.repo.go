@@ -2,38 +2,105 @@ package repos
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
 	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
 type PostRepo struct {
-	db  *pgxpool.Pool
-	rdb *redis.Client
+	db    *pgxpool.Pool
+	rdb   *redis.Client
+	store storage.Storage
 }
 
-func NewPostRepo(db *pgxpool.Pool, rdb *redis.Client) *PostRepo {
+func NewPostRepo(db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) *PostRepo {
 	return &PostRepo{
-		db:  db,
-		rdb: rdb,
+		db:    db,
+		rdb:   rdb,
+		store: store,
 	}
 }
 
+// CreatePost stores the post with its gallery and poll. A post with HiddenAt set is
+// stored hidden until a moderator reviews it. Status defaults to published.
 func (pr *PostRepo) CreatePost(c context.Context, post *models.Post) error {
-	query := `INSERT INTO posts (user_id, content_text, content_image, created_at)
-			  VALUES ($1, $2, $3, now()) returning id`
-	err := pr.db.QueryRow(c, query, post.UserID, post.Content, post.Image).Scan(&post.ID)
-	return err
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if post.Status == "" {
+		post.Status = models.PostPublished
+	}
+
+	query := `INSERT INTO posts (user_id, content_text, visibility, content_warning, hidden_at, status, publish_at, link_url, created_at)
+			  VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN now() END, $6, $7::timestamptz, $8, now()) returning id, created_at, hidden_at, publish_at`
+	if err := tx.QueryRow(c, query, post.UserID, post.Content, post.Visibility, post.ContentWarning, post.HiddenAt != nil, post.Status, post.PublishAt, post.LinkURL).
+		Scan(&post.ID, &post.CreatedAt, &post.HiddenAt, &post.PublishAt); err != nil {
+		return err
+	}
+
+	for i := range post.Media {
+		post.Media[i].PostID = post.ID
+		post.Media[i].Position = i
+		if err := insertPostMedia(c, tx, &post.Media[i]); err != nil {
+			return err
+		}
+	}
+
+	if post.Poll != nil {
+		post.Poll.PostID = post.ID
+		if err := insertPoll(c, tx, post.Poll); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(c)
+}
+
+const feedCachePrefix = "posts:all:"
+
+// feedCacheKey is the cache key of the feed as seen by one viewer; blocks and
+// mutes make the feed differ from user to user.
+func feedCacheKey(viewerId int) string {
+	return fmt.Sprintf("%s%d", feedCachePrefix, viewerId)
+}
+
+// clearFeedCaches drops the cached feeds of every viewer. A post that is
+// deleted, hidden or given a narrower audience may sit in anybody's feed and
+// must not be served from the cache to viewers who lost access to it.
+func clearFeedCaches(c context.Context, rdb *redis.Client) {
+	const batch = 500
+	keys := make([]string, 0, batch)
+	iter := rdb.Scan(c, 0, feedCachePrefix+"*", batch).Iterator()
+	for iter.Next(c) {
+		keys = append(keys, iter.Val())
+		if len(keys) == batch {
+			rdb.Unlink(c, keys...)
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Println("Failed to clear feed caches.\nCause:", err.Error())
+	}
+	if len(keys) > 0 {
+		rdb.Unlink(c, keys...)
+	}
 }
 
-func (pr *PostRepo) GetAllPosts(c context.Context) ([]dtos.PostResponse, error) {
-	const redisKey = "posts:all"
+func (pr *PostRepo) GetAllPosts(c context.Context, viewerId int) ([]dtos.PostResponse, error) {
+	redisKey := feedCacheKey(viewerId)
 
 	var posts []dtos.PostResponse
 
@@ -45,11 +112,13 @@ func (pr *PostRepo) GetAllPosts(c context.Context) ([]dtos.PostResponse, error)
 		return posts, nil
 	}
 
-	query := `SELECT id, user_id, content_text, content_image, created_at, updated_at, deleted_at 
-	          FROM posts 
-	          WHERE deleted_at IS NULL
-	          ORDER BY COALESCE(updated_at, created_at) DESC`
-	rows, err := pr.db.Query(c, query)
+	query := fmt.Sprintf(`SELECT id, user_id, content_text, visibility, content_warning, revision_count, status, publish_at, pinned_at IS NOT NULL, %s, created_at, updated_at, deleted_at 
+	          FROM posts p
+	          WHERE deleted_at IS NULL AND status = 'published' AND %s AND %s AND %s AND %s AND %s AND %s AND %s
+	          ORDER BY COALESCE(updated_at, created_at) DESC`,
+		bookmarkedBy("p", "$1"), notBlocked("p.user_id", "$1"), notMuted("p.user_id", "$1"), visibleAccount("p.user_id", "$1"), postAudience("p", "$1", true), notHidden("p", "$1"), notSuspended("p.user_id"),
+		noMutedWords("p.content_text", "$1"))
+	rows, err := pr.db.Query(c, query, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -57,12 +126,16 @@ func (pr *PostRepo) GetAllPosts(c context.Context) ([]dtos.PostResponse, error)
 
 	for rows.Next() {
 		var p dtos.PostResponse
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Image, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.BookmarkedByMe, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
 			return nil, err
 		}
 		posts = append(posts, p)
 	}
 
+	if err := pr.attachDetails(c, posts, viewerId); err != nil {
+		return nil, err
+	}
+
 	if err := utils.SetRedis(c, pr.rdb, redisKey, posts, time.Minute); err != nil {
 		return posts, nil
 	}
@@ -70,72 +143,768 @@ func (pr *PostRepo) GetAllPosts(c context.Context) ([]dtos.PostResponse, error)
 	return posts, nil
 }
 
-func (pr *PostRepo) GetPostsByUser(c context.Context, userId int) ([]dtos.PostResponse, error) {
-	query := `SELECT id, user_id, content_text, content_image, created_at, updated_at, deleted_at 
-	          FROM posts 
-	          WHERE user_id=$1 AND deleted_at IS NULL
-	          ORDER BY created_at DESC`
-	rows, err := pr.db.Query(c, query, userId)
+// GetPostsByUser returns a page of the published posts listed on a user's
+// profile as seen by viewerId, following filter (one of the models.Profile*
+// filters). The plain listing puts pinned posts first, most recently pinned
+// on top.
+func (pr *PostRepo) GetPostsByUser(c context.Context, userId, viewerId int, filter string, limit, offset int) ([]dtos.PostResponse, error) {
+	listed := "p.user_id=$1"
+	order := "p.pinned_at DESC NULLS LAST, p.created_at DESC"
+	switch filter {
+	case models.ProfileMedia:
+		listed = "p.user_id=$1 AND EXISTS (SELECT 1 FROM post_media pm WHERE pm.post_id = p.id)"
+		order = "p.created_at DESC"
+	case models.ProfileReplies:
+		// a post the user commented on is listed from their latest comment
+		listed = "(p.user_id=$1 OR EXISTS (SELECT 1 FROM comments rc WHERE rc.post_id = p.id AND rc.user_id=$1 AND rc.hidden_at IS NULL))"
+		order = "GREATEST(p.created_at, (SELECT max(rc.created_at) FROM comments rc WHERE rc.post_id = p.id AND rc.user_id=$1 AND rc.hidden_at IS NULL)) DESC"
+	}
+
+	query := fmt.Sprintf(`SELECT id, user_id, content_text, visibility, content_warning, revision_count, status, publish_at, pinned_at IS NOT NULL, %s, created_at, updated_at, deleted_at 
+	          FROM posts p
+	          WHERE %s AND deleted_at IS NULL AND status = 'published' AND %s AND %s AND %s AND %s AND %s
+	          ORDER BY %s
+	          LIMIT $3 OFFSET $4`,
+		bookmarkedBy("p", "$2"), listed, notBlocked("p.user_id", "$2"), visibleAccount("p.user_id", "$2"), postAudience("p", "$2", false), notHidden("p", "$2"), notSuspended("p.user_id"), order)
+	rows, err := pr.db.Query(c, query, userId, viewerId, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []dtos.PostResponse
+	posts := []dtos.PostResponse{}
 	for rows.Next() {
 		var p dtos.PostResponse
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Image, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.BookmarkedByMe, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
 			return nil, err
 		}
 		posts = append(posts, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := pr.attachDetails(c, posts, viewerId); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
-func (pr *PostRepo) GetPostByID(c context.Context, id int) (*dtos.PostResponse, error) {
-	query := `SELECT id, user_id, content_text, content_image, created_at, updated_at, deleted_at 
-	          FROM posts 
-	          WHERE id=$1 AND deleted_at IS NULL`
+// GetPostByID returns the post as seen by viewerId. Posts of users who are in a
+// block with the viewer, of private accounts the viewer does not follow, whose
+// audience leaves the viewer out, hidden by a moderator, not published yet or
+// written by a suspended user are reported as not found.
+func (pr *PostRepo) GetPostByID(c context.Context, id, viewerId int) (*dtos.PostResponse, error) {
+	query := fmt.Sprintf(`SELECT id, user_id, content_text, visibility, content_warning, revision_count, status, publish_at, pinned_at IS NOT NULL, %s, created_at, updated_at, deleted_at 
+	          FROM posts p
+	          WHERE id=$1 AND deleted_at IS NULL AND %s AND %s AND %s AND %s AND %s AND %s`,
+		bookmarkedBy("p", "$2"), notBlocked("p.user_id", "$2"), visibleAccount("p.user_id", "$2"), postAudience("p", "$2", false), notHidden("p", "$2"), published("p", "$2"), notSuspended("p.user_id"))
 	var p dtos.PostResponse
-	if err := pr.db.QueryRow(c, query, id).Scan(&p.ID, &p.UserID, &p.Content, &p.Image, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+	if err := pr.db.QueryRow(c, query, id, viewerId).Scan(&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.BookmarkedByMe, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
 		return nil, err
 	}
-	return &p, nil
+
+	posts := []dtos.PostResponse{p}
+	if err := pr.attachDetails(c, posts, viewerId); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
 }
 
-func (pr *PostRepo) UpdatePost(c context.Context, post *models.Post) error {
+// ErrEditWindowClosed is returned when the content or gallery of a post is
+// changed after its edit window.
+var ErrEditWindowClosed = errors.New("post can no longer be edited")
+
+// UpdatePost applies the changes set on post: its content with the warning
+// and HiddenAt that go with it, its visibility and, when Media is not nil, its
+// gallery (see setMedia). Changing the content or gallery keeps the version it
+// replaces as a revision. Once the post is older than editWindow that is
+// refused with ErrEditWindowClosed; a zero editWindow never closes. Drafts and
+// scheduled posts are changed freely without revisions.
+func (pr *PostRepo) UpdatePost(c context.Context, post *models.Post, editWindow time.Duration) error {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var (
+		content *string
+		status  string
+		closed  bool
+	)
+	query := `SELECT content_text, status, $2::float8 > 0 AND created_at < now() - make_interval(secs => $2::float8)
+	          FROM posts WHERE id=$1 FOR UPDATE`
+	if err := tx.QueryRow(c, query, post.ID, editWindow.Seconds()).Scan(&content, &status, &closed); err != nil {
+		return err
+	}
+
+	edited := status == models.PostPublished &&
+		(post.Media != nil || (post.Content != nil && (content == nil || *content != *post.Content)))
+	if edited {
+		if closed {
+			return ErrEditWindowClosed
+		}
+		if err := saveRevision(c, tx, post.ID); err != nil {
+			return err
+		}
+	}
+
 	setClauses := []string{}
 	args := []interface{}{}
 	argID := 1
 
 	if post.Content != nil {
-		setClauses = append(setClauses, fmt.Sprintf("content_text=$%d", argID))
-		args = append(args, post.Content)
-		argID++
+		// the warning and the previewed link belong to the text they were given for
+		setClauses = append(setClauses, fmt.Sprintf("content_text=$%d, content_warning=$%d, link_url=$%d", argID, argID+1, argID+2))
+		args = append(args, post.Content, post.ContentWarning, post.LinkURL)
+		argID += 3
 	}
 
-	if post.Image != nil {
-		setClauses = append(setClauses, fmt.Sprintf("content_image=$%d", argID))
-		args = append(args, post.Image)
+	if post.Visibility != "" {
+		setClauses = append(setClauses, fmt.Sprintf("visibility=$%d", argID))
+		args = append(args, post.Visibility)
 		argID++
 	}
 
-	if len(setClauses) == 0 {
+	if edited {
+		setClauses = append(setClauses, "revision_count=revision_count+1")
+	}
+
+	if len(setClauses) == 0 && post.Media == nil {
 		return nil
 	}
 
+	if post.HiddenAt != nil {
+		setClauses = append(setClauses, "hidden_at=now()")
+	}
 	setClauses = append(setClauses, "updated_at=now()")
 
-	query := fmt.Sprintf(`UPDATE posts SET %s WHERE id=$%d`,
+	query = fmt.Sprintf(`UPDATE posts SET %s WHERE id=$%d`,
 		strings.Join(setClauses, ", "), argID)
 	args = append(args, post.ID)
 
-	_, err := pr.db.Exec(c, query, args...)
+	if _, err := tx.Exec(c, query, args...); err != nil {
+		return err
+	}
+	if post.Media != nil {
+		if err := setMedia(c, tx, post.ID, post.Media); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(c); err != nil {
+		return err
+	}
+	if post.Visibility != "" || post.HiddenAt != nil {
+		clearFeedCaches(c, pr.rdb)
+	}
+	return nil
+}
+
+// saveRevision keeps the current content and gallery of a post as its next
+// revision. A version was shown from the time the one before it was replaced,
+// or the post was created.
+func saveRevision(c context.Context, tx pgx.Tx, postId int) error {
+	var revisionId int
+	query := `INSERT INTO post_revisions (post_id, revision, content_text, content_warning, created_at, replaced_at)
+	          SELECT p.id, p.revision_count + 1, p.content_text, p.content_warning,
+	                 COALESCE((SELECT max(r.replaced_at) FROM post_revisions r WHERE r.post_id = p.id), p.created_at, now()), now()
+	          FROM posts p WHERE p.id=$1
+	          RETURNING id`
+	if err := tx.QueryRow(c, query, postId).Scan(&revisionId); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO post_revision_media (revision_id, position, filename, variants, alt_text)
+	         SELECT $1, position, filename, variants, alt_text FROM post_media WHERE post_id=$2`
+	_, err := tx.Exec(c, query, revisionId, postId)
 	return err
 }
 
+// GetRevisions returns the versions of a post replaced by edits, newest first,
+// with their galleries.
+func (pr *PostRepo) GetRevisions(c context.Context, postId int) ([]models.PostRevision, error) {
+	query := `SELECT id, post_id, revision, content_text, content_warning, created_at, replaced_at
+	          FROM post_revisions WHERE post_id=$1
+	          ORDER BY revision DESC`
+	rows, err := pr.db.Query(c, query, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.PostRevision{}
+	byId := map[int]int{}
+	for rows.Next() {
+		var r models.PostRevision
+		if err := rows.Scan(&r.ID, &r.PostID, &r.Revision, &r.Content, &r.ContentWarning, &r.CreatedAt, &r.ReplacedAt); err != nil {
+			return nil, err
+		}
+		r.Media = []models.PostMedia{}
+		byId[r.ID] = len(revisions)
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return revisions, nil
+	}
+
+	ids := make([]int, 0, len(revisions))
+	for _, r := range revisions {
+		ids = append(ids, r.ID)
+	}
+	query = `SELECT id, revision_id, position, filename, variants, alt_text
+	         FROM post_revision_media WHERE revision_id = ANY($1)
+	         ORDER BY revision_id, position, id`
+	mediaRows, err := pr.db.Query(c, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer mediaRows.Close()
+	for mediaRows.Next() {
+		var (
+			revisionId int
+			m          models.PostMedia
+		)
+		if err := mediaRows.Scan(&m.ID, &revisionId, &m.Position, &m.Filename, &m.Variants, &m.AltText); err != nil {
+			return nil, err
+		}
+		m.PostID = postId
+		r := &revisions[byId[revisionId]]
+		r.Media = append(r.Media, m)
+	}
+	return revisions, mediaRows.Err()
+}
+
+// GetMedia returns the gallery of a post in display order.
+func (pr *PostRepo) GetMedia(c context.Context, postId int) ([]models.PostMedia, error) {
+	query := `SELECT id, post_id, position, filename, variants, alt_text, created_at
+	          FROM post_media WHERE post_id=$1
+	          ORDER BY position, id`
+	rows, err := pr.db.Query(c, query, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []models.PostMedia{}
+	for rows.Next() {
+		var m models.PostMedia
+		if err := rows.Scan(&m.ID, &m.PostID, &m.Position, &m.Filename, &m.Variants, &m.AltText, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// setMedia replaces the gallery of a post with media, in that order. Images
+// with an ID are kept and moved, those without are added and every other
+// image of the post is removed.
+func setMedia(c context.Context, tx pgx.Tx, postId int, media []models.PostMedia) error {
+	kept := []int{}
+	for _, m := range media {
+		if m.ID != 0 {
+			kept = append(kept, m.ID)
+		}
+	}
+	if _, err := tx.Exec(c, `DELETE FROM post_media WHERE post_id=$1 AND NOT (id = ANY($2))`, postId, kept); err != nil {
+		return err
+	}
+
+	for i := range media {
+		media[i].PostID = postId
+		media[i].Position = i
+		if media[i].ID != 0 {
+			if _, err := tx.Exec(c, `UPDATE post_media SET position=$1 WHERE id=$2 AND post_id=$3`, i, media[i].ID, postId); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := insertPostMedia(c, tx, &media[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertPostMedia adds an image to a post, attaching the upload it comes from.
+// It returns ErrUploadUnavailable when that upload is not pending anymore.
+func insertPostMedia(c context.Context, tx pgx.Tx, m *models.PostMedia) error {
+	if m.UploadID != 0 {
+		query := `UPDATE media_uploads SET status = 'attached', post_id=$1
+		          WHERE id=$2 AND status = 'pending' AND expires_at > now()`
+		tag, err := tx.Exec(c, query, m.PostID, m.UploadID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrUploadUnavailable
+		}
+	}
+
+	query := `INSERT INTO post_media (post_id, position, filename, variants, alt_text, created_at)
+	          VALUES ($1, $2, $3, $4, $5, now())
+	          RETURNING id, created_at`
+	return tx.QueryRow(c, query, m.PostID, m.Position, m.Filename, m.Variants, m.AltText).Scan(&m.ID, &m.CreatedAt)
+}
+
+// DeletePost moves a post to the trash. It loses its pin, so restoring it
+// cannot take the user past the pin limit.
 func (pr *PostRepo) DeletePost(c context.Context, postId int) error {
-	query := `UPDATE posts SET deleted_at = now() WHERE id=$1`
-	_, err := pr.db.Exec(c, query, postId)
-	return err
+	query := `UPDATE posts SET deleted_at = now(), pinned_at = NULL WHERE id=$1`
+	if _, err := pr.db.Exec(c, query, postId); err != nil {
+		return err
+	}
+	clearFeedCaches(c, pr.rdb)
+	return nil
+}
+
+// GetTrash returns the posts the user deleted that can still be restored,
+// most recently deleted first.
+func (pr *PostRepo) GetTrash(c context.Context, userId int, retention time.Duration) ([]dtos.PostResponse, error) {
+	query := fmt.Sprintf(`SELECT id, user_id, content_text, visibility, content_warning, revision_count, status, publish_at, pinned_at IS NOT NULL, %s, created_at, updated_at, deleted_at,
+	                 deleted_at + make_interval(secs => $2)
+	          FROM posts p
+	          WHERE user_id=$1 AND deleted_at > now() - make_interval(secs => $2)
+	          ORDER BY deleted_at DESC`, bookmarkedBy("p", "$1"))
+	rows, err := pr.db.Query(c, query, userId, retention.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []dtos.PostResponse{}
+	for rows.Next() {
+		var p dtos.PostResponse
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.BookmarkedByMe, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.PurgeAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := pr.attachDetails(c, posts, userId); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// RestorePost brings back a post the user deleted less than retention ago. It
+// returns pgx.ErrNoRows when there is no such post.
+func (pr *PostRepo) RestorePost(c context.Context, postId, userId int, retention time.Duration) error {
+	query := `UPDATE posts SET deleted_at = NULL
+	          WHERE id=$1 AND user_id=$2 AND deleted_at > now() - make_interval(secs => $3)`
+	tag, err := pr.db.Exec(c, query, postId, userId, retention.Seconds())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	clearFeedCaches(c, pr.rdb)
+	return nil
+}
+
+// GetDrafts returns the drafts and scheduled posts of the user, the next to
+// be published first and then the most recently changed drafts.
+func (pr *PostRepo) GetDrafts(c context.Context, userId int) ([]dtos.PostResponse, error) {
+	query := `SELECT id, user_id, content_text, visibility, content_warning, revision_count, status, publish_at, pinned_at IS NOT NULL, false, created_at, updated_at, deleted_at
+	          FROM posts
+	          WHERE user_id=$1 AND deleted_at IS NULL AND status IN ('draft', 'scheduled')
+	          ORDER BY publish_at NULLS LAST, COALESCE(updated_at, created_at) DESC`
+	rows, err := pr.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []dtos.PostResponse{}
+	for rows.Next() {
+		var p dtos.PostResponse
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Content, &p.Visibility, &p.ContentWarning, &p.RevisionCount, &p.Status, &p.PublishAt, &p.Pinned, &p.BookmarkedByMe, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := pr.attachDetails(c, posts, userId); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// SchedulePost sets when a draft or scheduled post of the user is published.
+// A nil publishAt cancels the schedule and turns the post back into a draft.
+// It returns pgx.ErrNoRows when the user has no such unpublished post, or
+// ErrPollWindow when the post's poll does not fit the new publish time.
+func (pr *PostRepo) SchedulePost(c context.Context, postId, userId int, publishAt *time.Time) error {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if publishAt != nil {
+		if err := lockUnpublished(c, tx, postId, userId, "$3::timestamptz", publishAt); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE posts
+	          SET status = CASE WHEN $3::timestamptz IS NULL THEN 'draft' ELSE 'scheduled' END, publish_at = $3::timestamptz
+	          WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL AND status IN ('draft', 'scheduled')`
+	tag, err := tx.Exec(c, query, postId, userId, publishAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(c)
+}
+
+// PublishPost publishes a draft or scheduled post of the user right away, as
+// if it was created now. It returns whether the post is hidden for review,
+// pgx.ErrNoRows when the user has no such unpublished post, or ErrPollWindow
+// when the post's poll does not fit being published now.
+func (pr *PostRepo) PublishPost(c context.Context, postId, userId int) (hidden bool, err error) {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(c)
+
+	if err := lockUnpublished(c, tx, postId, userId, "now()"); err != nil {
+		return false, err
+	}
+
+	query := `UPDATE posts SET status = 'published', publish_at = NULL, created_at = now()
+	          WHERE id=$1 AND user_id=$2
+	          RETURNING hidden_at IS NOT NULL`
+	if err := tx.QueryRow(c, query, postId, userId).Scan(&hidden); err != nil {
+		return false, err
+	}
+	return hidden, tx.Commit(c)
+}
+
+// lockUnpublished locks a draft or scheduled post of the user and checks that
+// its poll fits publishing it at publishAt, a SQL expression that may refer to
+// args from $3 on. It returns pgx.ErrNoRows when the user has no such
+// unpublished post and ErrPollWindow when the poll does not fit.
+func lockUnpublished(c context.Context, tx pgx.Tx, postId, userId int, publishAt string, args ...any) error {
+	var outside bool
+	query := fmt.Sprintf(`SELECT %s FROM posts p
+	          WHERE p.id=$1 AND p.user_id=$2 AND p.deleted_at IS NULL AND p.status IN ('draft', 'scheduled')
+	          FOR UPDATE`, pollOutsideWindow("p", publishAt))
+	if err := tx.QueryRow(c, query, append([]any{postId, userId}, args...)...).Scan(&outside); err != nil {
+		return err
+	}
+	if outside {
+		return ErrPollWindow
+	}
+	return nil
+}
+
+// ErrPinLimit is returned when a user who pinned as many posts as allowed pins
+// another one.
+var ErrPinLimit = errors.New("too many pinned posts")
+
+// PinPost pins a published post of the user to their profile, unless they
+// already pinned maxPinned posts, in which case it returns ErrPinLimit. It
+// returns pgx.ErrNoRows when the user has no such post.
+func (pr *PostRepo) PinPost(c context.Context, postId, userId, maxPinned int) error {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	// pins of the same user wait for each other so the limit holds
+	if _, err := tx.Exec(c, `SELECT 1 FROM users WHERE id=$1 FOR UPDATE`, userId); err != nil {
+		return err
+	}
+
+	var pinned bool
+	query := `SELECT pinned_at IS NOT NULL FROM posts
+	          WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL AND status = 'published'`
+	if err := tx.QueryRow(c, query, postId, userId).Scan(&pinned); err != nil {
+		return err
+	}
+	if pinned {
+		return nil
+	}
+
+	var count int
+	query = `SELECT count(*) FROM posts WHERE user_id=$1 AND pinned_at IS NOT NULL AND deleted_at IS NULL`
+	if err := tx.QueryRow(c, query, userId).Scan(&count); err != nil {
+		return err
+	}
+	if count >= maxPinned {
+		return ErrPinLimit
+	}
+
+	if _, err := tx.Exec(c, `UPDATE posts SET pinned_at = now() WHERE id=$1`, postId); err != nil {
+		return err
+	}
+	return tx.Commit(c)
+}
+
+// UnpinPost takes a post of the user off their profile's pins. It returns
+// pgx.ErrNoRows when the user has no such pinned post.
+func (pr *PostRepo) UnpinPost(c context.Context, postId, userId int) error {
+	query := `UPDATE posts SET pinned_at = NULL WHERE id=$1 AND user_id=$2 AND pinned_at IS NOT NULL`
+	tag, err := pr.db.Exec(c, query, postId, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PublishDuePosts publishes the scheduled posts whose publish_at has come and
+// returns them with their UserID and HiddenAt set. It returns nothing when
+// another instance is publishing at the same time.
+func (pr *PostRepo) PublishDuePosts(c context.Context) ([]models.Post, error) {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	var locked bool
+	if err := tx.QueryRow(c, `SELECT pg_try_advisory_xact_lock($1)`, postSchedulerLock).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	// a post whose poll would already be closed goes back to the drafts for
+	// its author to fix
+	query := fmt.Sprintf(`UPDATE posts p SET status = 'draft', publish_at = NULL
+	          WHERE p.status = 'scheduled' AND p.publish_at <= now() AND p.deleted_at IS NULL AND %s
+	          RETURNING p.id`, pollOutsideWindow("p", "now()"))
+	rows, err := tx.Query(c, query)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		log.Printf("Scheduled post %d went back to the drafts, its poll does not fit the publish time\n", id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE posts SET status = 'published', publish_at = NULL, created_at = now()
+	          WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+	          RETURNING id, user_id, hidden_at`
+	rows, err = tx.Query(c, query)
+	if err != nil {
+		return nil, err
+	}
+	posts := []models.Post{}
+	for rows.Next() {
+		var p models.Post
+		if err := rows.Scan(&p.ID, &p.UserID, &p.HiddenAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Status = models.PostPublished
+		posts = append(posts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, tx.Commit(c)
+}
+
+// postPurgeBatch is how many posts one purge transaction removes.
+const postPurgeBatch = 500
+
+// PurgeDeletedPosts removes for good the posts deleted more than retention
+// ago together with their likes, bookmarks, polls, comments, mentions,
+// notifications, gallery and revisions. Their images are left to the media
+// collector, which deletes them once nothing else uses them. Reports stay
+// for the moderation history. It returns how many posts were purged, or
+// ErrJobRunning when another instance is purging.
+func (pr *PostRepo) PurgeDeletedPosts(c context.Context, retention time.Duration) (int, error) {
+	purged := 0
+	err := withJobLock(c, pr.db, postPurgeLock, func() error {
+		for {
+			n, err := pr.purgeBatch(c, retention)
+			purged += n
+			if err != nil || n < postPurgeBatch {
+				return err
+			}
+		}
+	})
+	return purged, err
+}
+
+func (pr *PostRepo) purgeBatch(c context.Context, retention time.Duration) (int, error) {
+	tx, err := pr.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, `SELECT id FROM posts
+	                          WHERE deleted_at < now() - make_interval(secs => $1)
+	                          ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, retention.Seconds(), postPurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	queries := []string{
+		`DELETE FROM likes WHERE post_id = ANY($1)`,
+		`DELETE FROM bookmarks WHERE post_id = ANY($1)`,
+		`DELETE FROM poll_votes WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ANY($1))`,
+		`DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ANY($1))`,
+		`DELETE FROM polls WHERE post_id = ANY($1)`,
+		`DELETE FROM mentions WHERE post_id = ANY($1)`,
+		`DELETE FROM notifications WHERE post_id = ANY($1)`,
+		`DELETE FROM comments WHERE post_id = ANY($1)`,
+		`DELETE FROM post_revision_media WHERE revision_id IN (SELECT id FROM post_revisions WHERE post_id = ANY($1))`,
+		`DELETE FROM post_revisions WHERE post_id = ANY($1)`,
+		`DELETE FROM post_media WHERE post_id = ANY($1)`,
+		`DELETE FROM media_uploads WHERE post_id = ANY($1)`,
+		`DELETE FROM posts WHERE id = ANY($1)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(c, query, ids); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit(c)
+}
+
+// StartPurge purges deleted posts past retention every interval in the
+// background.
+func (pr *PostRepo) StartPurge(interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c, cancel := context.WithTimeout(context.Background(), interval)
+			purged, err := pr.PurgeDeletedPosts(c, retention)
+			cancel()
+			if err != nil && !errors.Is(err, ErrJobRunning) {
+				log.Println("Post purge failed:", err)
+			}
+			if purged > 0 {
+				log.Printf("Post purge removed %d posts\n", purged)
+			}
+		}
+	}()
+}
+
+// attachDetails fills in the mentions, gallery, poll and link preview of the
+// posts, the poll as seen by viewerId.
+func (pr *PostRepo) attachDetails(c context.Context, posts []dtos.PostResponse, viewerId int) error {
+	ids := make([]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	mentions, err := loadMentions(c, pr.db, ids, false)
+	if err != nil {
+		return err
+	}
+	media, err := pr.loadMedia(c, ids)
+	if err != nil {
+		return err
+	}
+	polls, err := loadPolls(c, pr.db, ids, viewerId)
+	if err != nil {
+		return err
+	}
+	previews, err := loadLinkPreviews(c, pr.db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Edited = posts[i].RevisionCount > 0
+		setPostMedia(&posts[i], media[posts[i].ID])
+		posts[i].Poll = polls[posts[i].ID]
+		posts[i].LinkPreview = previews[posts[i].ID]
+		posts[i].Mentions = mentions[posts[i].ID]
+		if posts[i].Mentions == nil {
+			posts[i].Mentions = []dtos.MentionResponse{}
+		}
+	}
+	return nil
+}
+
+// loadMedia returns the galleries of the posts keyed by post id, with image
+// URLs filled in.
+func (pr *PostRepo) loadMedia(c context.Context, postIds []int) (map[int][]dtos.PostMediaResponse, error) {
+	query := `SELECT id, post_id, position, filename, variants, alt_text
+	          FROM post_media WHERE post_id = ANY($1)
+	          ORDER BY post_id, position, id`
+	rows, err := pr.db.Query(c, query, postIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := map[int][]dtos.PostMediaResponse{}
+	for rows.Next() {
+		var (
+			postId int
+			m      dtos.PostMediaResponse
+		)
+		if err := rows.Scan(&m.ID, &postId, &m.Position, &m.Filename, &m.Variants, &m.AltText); err != nil {
+			return nil, err
+		}
+		utils.SetImageURLs(pr.store, m.Variants)
+		media[postId] = append(media[postId], m)
+	}
+	return media, rows.Err()
+}
+
+// setPostMedia sets the gallery of the post, the first image doubling as the
+// post's image.
+func setPostMedia(post *dtos.PostResponse, media []dtos.PostMediaResponse) {
+	post.Media = media
+	if post.Media == nil {
+		post.Media = []dtos.PostMediaResponse{}
+	}
+	if len(media) > 0 {
+		post.Image = &media[0].Filename
+		post.ImageVariants = media[0].Variants
+	}
 }
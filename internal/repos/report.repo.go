@@ -0,0 +1,280 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrReportResolved is returned when resolving a report that is not open
+// anymore.
+var ErrReportResolved = errors.New("report already resolved")
+
+type ReportRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewReportRepo(db *pgxpool.Pool, rdb *redis.Client) *ReportRepo {
+	return &ReportRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// CreateReport files a report. It returns pgx.ErrNoRows when the reporter
+// already reported the same target.
+func (rr *ReportRepo) CreateReport(c context.Context, report *models.Report) error {
+	query := `INSERT INTO reports (reporter_id, target_type, target_id, reason, details, status, created_at)
+	          VALUES ($1, $2, $3, $4, $5, 'open', now())
+	          ON CONFLICT (reporter_id, target_type, target_id) DO NOTHING
+	          RETURNING id, status, created_at`
+	return rr.db.QueryRow(c, query, report.ReporterID, report.TargetType, report.TargetID, report.Reason, report.Details).
+		Scan(&report.ID, &report.Status, &report.CreatedAt)
+}
+
+// CreateSystemReport puts content held by screening in the moderation queue.
+func (rr *ReportRepo) CreateSystemReport(c context.Context, targetType string, targetId int, details string) error {
+	query := `INSERT INTO reports (target_type, target_id, reason, details, status, created_at)
+	          VALUES ($1, $2, $3, $4, 'open', now())`
+	_, err := rr.db.Exec(c, query, targetType, targetId, models.ReportReasonScreening, details)
+	return err
+}
+
+// TargetOwner returns the user behind a report target: the author of a post
+// or comment, or the user itself. For posts and comments it also returns the
+// post id. It returns pgx.ErrNoRows when the target does not exist.
+func (rr *ReportRepo) TargetOwner(c context.Context, targetType string, targetId int) (int, *int, error) {
+	var (
+		ownerId int
+		postId  *int
+		err     error
+	)
+	switch targetType {
+	case models.ReportTargetPost:
+		err = rr.db.QueryRow(c, `SELECT user_id, id FROM posts WHERE id=$1 AND deleted_at IS NULL`, targetId).Scan(&ownerId, &postId)
+	case models.ReportTargetComment:
+		err = rr.db.QueryRow(c, `SELECT user_id, post_id FROM comments WHERE id=$1`, targetId).Scan(&ownerId, &postId)
+	case models.ReportTargetUser:
+		err = rr.db.QueryRow(c, `SELECT id FROM users WHERE id=$1`, targetId).Scan(&ownerId)
+	default:
+		err = pgx.ErrNoRows
+	}
+	return ownerId, postId, err
+}
+
+func (rr *ReportRepo) GetReports(c context.Context, filter dtos.ReportFilter, limit, offset int) ([]dtos.ModerationQueueItem, error) {
+	conditions := []string{}
+	args := []any{}
+	for _, f := range []struct{ col, value string }{
+		{"r.status", filter.Status},
+		{"r.target_type", filter.TargetType},
+		{"r.reason", filter.Reason},
+	} {
+		if f.value != "" {
+			args = append(args, f.value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", f.col, len(args)))
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT r.id, r.reporter_id, r.target_type, r.target_id, r.reason, r.details, r.status,
+		       r.resolved_by, r.resolved_at, r.created_at,
+		       (SELECT count(*) FROM reports o
+		        WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status = 'open')
+		FROM reports r
+		%s
+		ORDER BY r.created_at ASC, r.id ASC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args))
+
+	rows, err := rr.db.Query(c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []dtos.ModerationQueueItem{}
+	for rows.Next() {
+		var r dtos.ModerationQueueItem
+		if err := rows.Scan(&r.ID, &r.ReporterID, &r.TargetType, &r.TargetID, &r.Reason, &r.Details, &r.Status,
+			&r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt, &r.OpenReports); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+func (rr *ReportRepo) GetReportByID(c context.Context, reportId int) (*models.Report, error) {
+	query := `SELECT id, reporter_id, target_type, target_id, reason, details, status, resolved_by, resolved_at, created_at
+	          FROM reports WHERE id=$1`
+
+	var r models.Report
+	if err := rr.db.QueryRow(c, query, reportId).Scan(&r.ID, &r.ReporterID, &r.TargetType, &r.TargetID, &r.Reason,
+		&r.Details, &r.Status, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ResolveReport closes an open report with the given status, applying and
+// recording the moderator action when there is one. Every other open report
+// about the same target is closed with it. It returns the reporters of the
+// closed reports so they can be told, or ErrReportResolved when the report
+// was already closed.
+func (rr *ReportRepo) ResolveReport(c context.Context, reportId, moderatorId int, status string, action *models.ModerationAction) ([]int, error) {
+	tx, err := rr.db.Begin(c)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	var (
+		targetType    string
+		targetId      int
+		currentStatus string
+	)
+	query := `SELECT target_type, target_id, status FROM reports WHERE id=$1 FOR UPDATE`
+	if err := tx.QueryRow(c, query, reportId).Scan(&targetType, &targetId, &currentStatus); err != nil {
+		return nil, err
+	}
+	if currentStatus != models.ReportOpen {
+		return nil, ErrReportResolved
+	}
+
+	if action != nil {
+		action.ModeratorID = moderatorId
+		action.TargetType = targetType
+		action.TargetID = targetId
+		action.ReportID = &reportId
+		if err := applyModerationAction(c, tx, action); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `UPDATE reports SET status = $1, resolved_by = $2, resolved_at = now()
+	         WHERE target_type = $3 AND target_id = $4 AND (status = 'open' OR id = $5)
+	         RETURNING reporter_id`
+	rows, err := tx.Query(c, query, status, moderatorId, targetType, targetId, reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	reporters := []int{}
+	for rows.Next() {
+		var id *int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// reports filed by screening have nobody to tell
+		if id != nil {
+			reporters = append(reporters, *id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, err
+	}
+	rr.afterAction(c, action)
+	return reporters, nil
+}
+
+// TakeAction applies and records a moderator action that does not come from a
+// report.
+func (rr *ReportRepo) TakeAction(c context.Context, action *models.ModerationAction) error {
+	tx, err := rr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if err := applyModerationAction(c, tx, action); err != nil {
+		return err
+	}
+	if err := tx.Commit(c); err != nil {
+		return err
+	}
+	rr.afterAction(c, action)
+	return nil
+}
+
+// afterAction drops the cached feeds when a post was hidden or brought back.
+func (rr *ReportRepo) afterAction(c context.Context, action *models.ModerationAction) {
+	if action == nil || action.TargetType != models.ReportTargetPost {
+		return
+	}
+	if action.Action == models.ModerationHide || action.Action == models.ModerationRestore {
+		clearFeedCaches(c, rr.rdb)
+	}
+}
+
+func (rr *ReportRepo) GetActions(c context.Context, targetType string, targetId, limit, offset int) ([]dtos.ModerationActionResponse, error) {
+	query := `SELECT id, moderator_id, action, target_type, target_id, report_id, reason, created_at
+	          FROM moderation_actions
+	          WHERE ($1 = '' OR target_type = $1) AND ($2 = 0 OR target_id = $2)
+	          ORDER BY created_at DESC, id DESC
+	          LIMIT $3 OFFSET $4`
+	rows, err := rr.db.Query(c, query, targetType, targetId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []dtos.ModerationActionResponse{}
+	for rows.Next() {
+		var a dtos.ModerationActionResponse
+		if err := rows.Scan(&a.ID, &a.ModeratorID, &a.Action, &a.TargetType, &a.TargetID, &a.ReportID, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+// applyModerationAction carries out the action on its target and writes it to
+// the moderation log. Warnings are delivered by the caller as notifications.
+func applyModerationAction(c context.Context, tx pgx.Tx, action *models.ModerationAction) error {
+	table := ""
+	switch action.TargetType {
+	case models.ReportTargetPost:
+		table = "posts"
+	case models.ReportTargetComment:
+		table = "comments"
+	}
+
+	switch action.Action {
+	case models.ModerationHide:
+		if _, err := tx.Exec(c, fmt.Sprintf(`UPDATE %s SET hidden_at = now() WHERE id=$1`, table), action.TargetID); err != nil {
+			return err
+		}
+	case models.ModerationRestore:
+		if _, err := tx.Exec(c, fmt.Sprintf(`UPDATE %s SET hidden_at = NULL WHERE id=$1`, table), action.TargetID); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO moderation_actions (moderator_id, action, target_type, target_id, report_id, reason, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, now())
+	          RETURNING id, created_at`
+	return tx.QueryRow(c, query, action.ModeratorID, action.Action, action.TargetType, action.TargetID, action.ReportID, action.Reason).
+		Scan(&action.ID, &action.CreatedAt)
+}
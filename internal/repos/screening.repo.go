@@ -0,0 +1,175 @@
+package repos
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const screeningRulesKey = "screening:rules"
+
+// linkHost finds the host of links in text, with or without a scheme.
+var linkHost = regexp.MustCompile(`(?i)(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})`)
+
+// ScreeningRepo stores the blocked word, regex and domain lists managed by
+// moderators and screens text against them.
+type ScreeningRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+	// compiled holds the regexp of every word and regex rule seen so far by
+	// rule id, nil for a regex that does not compile
+	compiled sync.Map
+}
+
+func NewScreeningRepo(db *pgxpool.Pool, rdb *redis.Client) *ScreeningRepo {
+	return &ScreeningRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// CreateRule adds a rule. Words and domains are stored lowercase. It returns
+// pgx.ErrNoRows when the same rule already exists.
+func (sr *ScreeningRepo) CreateRule(c context.Context, rule *models.ScreeningRule) error {
+	if rule.Kind != models.ScreenRegex {
+		rule.Pattern = strings.ToLower(strings.TrimSpace(rule.Pattern))
+	}
+
+	query := `INSERT INTO screening_rules (kind, pattern, outcome, created_by, created_at)
+	          VALUES ($1, $2, $3, $4, now())
+	          ON CONFLICT (kind, pattern) DO NOTHING
+	          RETURNING id, created_at`
+	if err := sr.db.QueryRow(c, query, rule.Kind, rule.Pattern, rule.Outcome, rule.CreatedBy).Scan(&rule.ID, &rule.CreatedAt); err != nil {
+		return err
+	}
+
+	sr.rdb.Del(c, screeningRulesKey)
+	return nil
+}
+
+func (sr *ScreeningRepo) DeleteRule(c context.Context, ruleId int) (int64, error) {
+	cmdTag, err := sr.db.Exec(c, `DELETE FROM screening_rules WHERE id=$1`, ruleId)
+	if err != nil {
+		return 0, err
+	}
+
+	sr.rdb.Del(c, screeningRulesKey)
+	return cmdTag.RowsAffected(), nil
+}
+
+func (sr *ScreeningRepo) GetRules(c context.Context) ([]models.ScreeningRule, error) {
+	var rules []models.ScreeningRule
+	found, err := utils.GetRedis(c, sr.rdb, screeningRulesKey, &rules)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		sr.compile(rules)
+		return rules, nil
+	}
+
+	query := `SELECT id, kind, pattern, outcome, created_by, created_at
+	          FROM screening_rules
+	          ORDER BY kind, pattern`
+	rows, err := sr.db.Query(c, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules = []models.ScreeningRule{}
+	for rows.Next() {
+		var r models.ScreeningRule
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Pattern, &r.Outcome, &r.CreatedBy, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sr.compile(rules)
+	if err := utils.SetRedis(c, sr.rdb, screeningRulesKey, rules, time.Minute); err != nil {
+		return rules, nil
+	}
+	return rules, nil
+}
+
+// compile compiles the word and regex rules not compiled yet. A rule never
+// changes once created, so its regexp is kept for as long as the app runs.
+func (sr *ScreeningRepo) compile(rules []models.ScreeningRule) {
+	for _, rule := range rules {
+		if _, ok := sr.compiled.Load(rule.ID); ok {
+			continue
+		}
+		var (
+			re  *regexp.Regexp
+			err error
+		)
+		switch rule.Kind {
+		case models.ScreenWord:
+			re, err = regexp.Compile(`(?i)(?:^|[^\pL\pN_])` + regexp.QuoteMeta(rule.Pattern) + `(?:$|[^\pL\pN_])`)
+		case models.ScreenRegex:
+			re, err = regexp.Compile(rule.Pattern)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("Skipping screening rule %d with invalid regex.\nCause: %s", rule.ID, err.Error())
+		}
+		sr.compiled.Store(rule.ID, re)
+	}
+}
+
+// Screen checks text against every rule and returns the strictest outcome of
+// the rules it breaks.
+func (sr *ScreeningRepo) Screen(c context.Context, text string) (utils.ScreenResult, error) {
+	result := utils.ScreenResult{Outcome: models.ScreenAllow}
+	if strings.TrimSpace(text) == "" {
+		return result, nil
+	}
+
+	rules, err := sr.GetRules(c)
+	if err != nil {
+		return result, err
+	}
+
+	var hosts []string
+	for _, m := range linkHost.FindAllStringSubmatch(text, -1) {
+		hosts = append(hosts, strings.ToLower(m[1]))
+	}
+
+	for _, rule := range rules {
+		if !sr.ruleMatches(rule, text, hosts) {
+			continue
+		}
+		result.Outcome = utils.StricterOutcome(result.Outcome, rule.Outcome)
+		result.Matches = append(result.Matches, rule.Kind+":"+rule.Pattern)
+	}
+	return result, nil
+}
+
+func (sr *ScreeningRepo) ruleMatches(rule models.ScreeningRule, text string, hosts []string) bool {
+	switch rule.Kind {
+	case models.ScreenWord, models.ScreenRegex:
+		v, _ := sr.compiled.Load(rule.ID)
+		re, _ := v.(*regexp.Regexp)
+		return re != nil && re.MatchString(text)
+	case models.ScreenDomain:
+		for _, h := range hosts {
+			if h == rule.Pattern || strings.HasSuffix(h, "."+rule.Pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// activeSuspension matches users whose suspension has not run out yet.
+const activeSuspension = `suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > now())`
+
+type SuspensionRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewSuspensionRepo(db *pgxpool.Pool, rdb *redis.Client) *SuspensionRepo {
+	return &SuspensionRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// Suspend suspends the user for the given number of hours, or for good when
+// hours is nil, replacing any earlier suspension. Existing sessions stop
+// working right away. It returns pgx.ErrNoRows when the user does not exist.
+func (sr *SuspensionRepo) Suspend(c context.Context, userId int, hours *int, reason string) (*dtos.SuspensionResponse, error) {
+	query := `UPDATE users
+	          SET suspended_at = now(),
+	              suspended_until = now() + make_interval(hours => $2::int),
+	              suspension_reason = $3
+	          WHERE id = $1
+	          RETURNING id, suspended_at, suspended_until, suspension_reason`
+
+	var s dtos.SuspensionResponse
+	if err := sr.db.QueryRow(c, query, userId, hours, reason).Scan(&s.UserID, &s.SuspendedAt, &s.SuspendedUntil, &s.Reason); err != nil {
+		return nil, err
+	}
+
+	var ttl time.Duration
+	if hours != nil {
+		ttl = time.Duration(*hours) * time.Hour
+	}
+	if err := utils.MarkSuspendedRedis(c, sr.rdb, userId, ttl); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Unsuspend lifts the suspension of the user.
+func (sr *SuspensionRepo) Unsuspend(c context.Context, userId int) (int64, error) {
+	query := `UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL
+	          WHERE id = $1 AND ` + activeSuspension
+	cmdTag, err := sr.db.Exec(c, query, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := sr.rdb.Del(c, utils.SuspendedKey(userId)).Err(); err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// IsSuspended tells whether the user is suspended. The Redis flag answers
+// most calls; when it is missing, because it expired, was evicted or was never
+// written, the database decides and the answer is cached for
+// utils.SuspensionCheckTTL.
+func (sr *SuspensionRepo) IsSuspended(c context.Context, userId int) (bool, error) {
+	flag, err := sr.rdb.Get(c, utils.SuspendedKey(userId)).Result()
+	if err == nil {
+		return flag == "true", nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	var suspended bool
+	query := `SELECT ` + activeSuspension + ` FROM users WHERE id = $1`
+	if err := sr.db.QueryRow(c, query, userId).Scan(&suspended); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	if suspended {
+		// a short TTL, so a stale read racing Unsuspend does not lock the
+		// user out for the rest of the suspension
+		err = utils.MarkSuspendedRedis(c, sr.rdb, userId, utils.SuspensionCheckTTL)
+	} else {
+		// NX keeps a suspension written meanwhile
+		err = sr.rdb.SetNX(c, utils.SuspendedKey(userId), "false", utils.SuspensionCheckTTL).Err()
+	}
+	if err != nil {
+		log.Println("Failed to cache suspension state.\nCause:", err.Error())
+	}
+	return suspended, nil
+}
+
+// GetSuspension returns the running suspension of the user, or nil when the
+// user is not suspended.
+func (sr *SuspensionRepo) GetSuspension(c context.Context, userId int) (*dtos.SuspensionResponse, error) {
+	query := `SELECT id, suspended_at, suspended_until, suspension_reason
+	          FROM users WHERE id = $1 AND ` + activeSuspension
+
+	var s dtos.SuspensionResponse
+	err := sr.db.QueryRow(c, query, userId).Scan(&s.UserID, &s.SuspendedAt, &s.SuspendedUntil, &s.Reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateAppeal files an appeal against the user's suspension. It returns
+// pgx.ErrNoRows when the user already has an appeal waiting for review.
+func (sr *SuspensionRepo) CreateAppeal(c context.Context, appeal *models.Appeal) error {
+	query := `INSERT INTO appeals (user_id, message, status, created_at)
+	          VALUES ($1, $2, 'open', now())
+	          ON CONFLICT (user_id) WHERE status = 'open' DO NOTHING
+	          RETURNING id, status, created_at`
+	return sr.db.QueryRow(c, query, appeal.UserID, appeal.Message).Scan(&appeal.ID, &appeal.Status, &appeal.CreatedAt)
+}
+
+// GetAppeals lists appeals oldest first, with the suspension they are about
+// when it is still running.
+func (sr *SuspensionRepo) GetAppeals(c context.Context, status string, limit, offset int) ([]dtos.AppealResponse, error) {
+	query := `SELECT a.id, a.user_id, a.message, a.status, a.reviewed_by, a.reviewed_at, a.created_at,
+	                 ` + activeSuspension + `, u.suspended_at, u.suspended_until, u.suspension_reason
+	          FROM appeals a
+	          JOIN users u ON a.user_id = u.id
+	          WHERE ($1 = '' OR a.status = $1)
+	          ORDER BY a.created_at ASC, a.id ASC
+	          LIMIT $2 OFFSET $3`
+	rows, err := sr.db.Query(c, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appeals := []dtos.AppealResponse{}
+	for rows.Next() {
+		var (
+			a         dtos.AppealResponse
+			s         dtos.SuspensionResponse
+			suspended bool
+			at        *time.Time
+		)
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Message, &a.Status, &a.ReviewedBy, &a.ReviewedAt, &a.CreatedAt,
+			&suspended, &at, &s.SuspendedUntil, &s.Reason); err != nil {
+			return nil, err
+		}
+		if suspended {
+			s.UserID = a.UserID
+			s.SuspendedAt = *at
+			a.Suspension = &s
+		}
+		appeals = append(appeals, a)
+	}
+	return appeals, rows.Err()
+}
+
+// ResolveAppeal accepts or rejects an open appeal and returns its author. An
+// accepted appeal lifts the suspension. It returns pgx.ErrNoRows when there is
+// no open appeal with that id.
+func (sr *SuspensionRepo) ResolveAppeal(c context.Context, appealId, moderatorId int, status string) (int, error) {
+	tx, err := sr.db.Begin(c)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var userId int
+	query := `UPDATE appeals SET status = $2, reviewed_by = $3, reviewed_at = now()
+	          WHERE id = $1 AND status = 'open'
+	          RETURNING user_id`
+	if err := tx.QueryRow(c, query, appealId, status, moderatorId).Scan(&userId); err != nil {
+		return 0, err
+	}
+
+	if status == models.AppealAccepted {
+		query = `UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL WHERE id = $1`
+		if _, err := tx.Exec(c, query, userId); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return 0, err
+	}
+
+	if status == models.AppealAccepted {
+		if err := sr.rdb.Del(c, utils.SuspendedKey(userId)).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return userId, nil
+}
@@ -0,0 +1,151 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUploadUnavailable is returned when an upload put into a post is not
+// pending anymore: it expired or went into another post meanwhile.
+var ErrUploadUnavailable = errors.New("media upload is no longer available")
+
+type UploadRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewUploadRepo(db *pgxpool.Pool, rdb *redis.Client) *UploadRepo {
+	return &UploadRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// uploadChunksKey holds the bytes of an upload while its chunks come in.
+func uploadChunksKey(id int) string {
+	return fmt.Sprintf("Mosting:upload:%d", id)
+}
+
+// appendChunk appends ARGV[2] to the received bytes when they end at offset
+// ARGV[1] and keeps them for another ARGV[3] milliseconds. It returns 1 and
+// the new length, or 0 and the current length when the offset is wrong.
+var appendChunk = redis.NewScript(`
+local length = redis.call("STRLEN", KEYS[1])
+if length ~= tonumber(ARGV[1]) then
+	return {0, length}
+end
+length = redis.call("APPEND", KEYS[1], ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return {1, length}
+`)
+
+// CreateUpload stores a new upload expiring after models.MediaUploadTTL.
+func (ur *UploadRepo) CreateUpload(c context.Context, upload *models.MediaUpload) error {
+	query := `INSERT INTO media_uploads (user_id, status, size_bytes, filename, variants, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, now() + make_interval(secs => $6), now())
+	          RETURNING id, expires_at, created_at`
+	return ur.db.QueryRow(c, query, upload.UserID, upload.Status, upload.SizeBytes, upload.Filename, upload.Variants, models.MediaUploadTTL.Seconds()).
+		Scan(&upload.ID, &upload.ExpiresAt, &upload.CreatedAt)
+}
+
+// GetUpload returns an upload of the user unless it expired, with the bytes
+// received so far.
+func (ur *UploadRepo) GetUpload(c context.Context, id, userId int) (*models.MediaUpload, error) {
+	query := `SELECT id, user_id, status, size_bytes, filename, variants, post_id, expires_at, created_at
+	          FROM media_uploads
+	          WHERE id=$1 AND user_id=$2 AND (status = 'attached' OR expires_at > now())`
+	var u models.MediaUpload
+	if err := ur.db.QueryRow(c, query, id, userId).
+		Scan(&u.ID, &u.UserID, &u.Status, &u.SizeBytes, &u.Filename, &u.Variants, &u.PostID, &u.ExpiresAt, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	u.Received = u.SizeBytes
+	if u.Status == models.UploadUploading {
+		received, err := ur.rdb.StrLen(c, uploadChunksKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		u.Received = received
+	}
+	return &u, nil
+}
+
+// GetPendingUploads returns the uploads among ids that belong to the user and
+// are waiting to be attached, in no particular order.
+func (ur *UploadRepo) GetPendingUploads(c context.Context, userId int, ids []int) ([]models.MediaUpload, error) {
+	query := `SELECT id, user_id, status, size_bytes, filename, variants, post_id, expires_at, created_at
+	          FROM media_uploads
+	          WHERE id = ANY($1) AND user_id=$2 AND status = 'pending' AND expires_at > now()`
+	rows, err := ur.db.Query(c, query, ids, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := []models.MediaUpload{}
+	for rows.Next() {
+		var u models.MediaUpload
+		if err := rows.Scan(&u.ID, &u.UserID, &u.Status, &u.SizeBytes, &u.Filename, &u.Variants, &u.PostID, &u.ExpiresAt, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		u.Received = u.SizeBytes
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// AppendChunk adds a chunk starting at offset to an upload in progress and
+// pushes its expiry back. Nothing is added when offset is not where the
+// received bytes end, and ok is false. It returns the bytes received so far
+// either way.
+func (ur *UploadRepo) AppendChunk(c context.Context, id int, offset int64, chunk []byte) (received int64, ok bool, err error) {
+	res, err := appendChunk.Run(c, ur.rdb, []string{uploadChunksKey(id)}, offset, chunk, models.MediaUploadTTL.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if res[0] == 0 {
+		return res[1], false, nil
+	}
+
+	query := `UPDATE media_uploads SET expires_at = now() + make_interval(secs => $1) WHERE id=$2`
+	if _, err := ur.db.Exec(c, query, models.MediaUploadTTL.Seconds(), id); err != nil {
+		return res[1], true, err
+	}
+	return res[1], true, nil
+}
+
+// GetChunks returns the bytes received for an upload.
+func (ur *UploadRepo) GetChunks(c context.Context, id int) ([]byte, error) {
+	return ur.rdb.Get(c, uploadChunksKey(id)).Bytes()
+}
+
+// CompleteUpload marks an upload whose image was stored as pending, filling
+// in its Filename, Variants and new expiry, and drops the received bytes.
+func (ur *UploadRepo) CompleteUpload(c context.Context, upload *models.MediaUpload) error {
+	query := `UPDATE media_uploads
+	          SET status = 'pending', filename=$1, variants=$2, expires_at = now() + make_interval(secs => $3)
+	          WHERE id=$4 AND status = 'uploading'
+	          RETURNING status, expires_at`
+	if err := ur.db.QueryRow(c, query, upload.Filename, upload.Variants, models.MediaUploadTTL.Seconds(), upload.ID).
+		Scan(&upload.Status, &upload.ExpiresAt); err != nil {
+		return err
+	}
+	upload.Received = upload.SizeBytes
+	return ur.rdb.Del(c, uploadChunksKey(upload.ID)).Err()
+}
+
+// FailUpload marks an upload whose image could not be processed as failed and
+// drops the received bytes.
+func (ur *UploadRepo) FailUpload(c context.Context, id int) error {
+	query := `UPDATE media_uploads SET status = 'failed' WHERE id=$1`
+	if _, err := ur.db.Exec(c, query, id); err != nil {
+		return err
+	}
+	return ur.rdb.Del(c, uploadChunksKey(id)).Err()
+}
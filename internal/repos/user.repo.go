@@ -6,21 +6,25 @@ import (
 
 	"github.com/Darari17/social-media/internal/dtos"
 	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepo struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	store storage.Storage
 }
 
-func NewUserRepo(db *pgxpool.Pool) *UserRepo {
+func NewUserRepo(db *pgxpool.Pool, store storage.Storage) *UserRepo {
 	return &UserRepo{
-		db: db,
+		db:    db,
+		store: store,
 	}
 }
 
 func (ur *UserRepo) GetAllUsers(c context.Context) ([]dtos.UserResponse, error) {
-	query := "select id, name, email, avatar, bio, created_at, updated_at from users"
+	query := "select id, name, username, email, avatar, bio, is_private, created_at, updated_at from users"
 
 	rows, err := ur.db.Query(c, query)
 	if err != nil {
@@ -31,7 +35,7 @@ func (ur *UserRepo) GetAllUsers(c context.Context) ([]dtos.UserResponse, error)
 	var users []dtos.UserResponse
 	for rows.Next() {
 		var user dtos.UserResponse
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Bio, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.Avatar, &user.Bio, &user.IsPrivate, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, err
 		}
 
@@ -42,13 +46,14 @@ func (ur *UserRepo) GetAllUsers(c context.Context) ([]dtos.UserResponse, error)
 }
 
 func (ur *UserRepo) GetUserByID(c context.Context, userId int) (*models.User, error) {
-	query := "select id, name, email, avatar, bio, created_at, updated_at from users where id = $1"
+	query := "select id, name, username, email, avatar, avatar_variants, bio, is_private, created_at, updated_at from users where id = $1"
 
 	var user models.User
 
-	if err := ur.db.QueryRow(c, query, userId).Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Bio, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := ur.db.QueryRow(c, query, userId).Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.Avatar, &user.AvatarVariants, &user.Bio, &user.IsPrivate, &user.CreatedAt, &user.UpdatedAt); err != nil {
 		return nil, err
 	}
+	utils.SetImageURLs(ur.store, user.AvatarVariants)
 
 	return &user, nil
 }
@@ -63,11 +68,16 @@ func (ur *UserRepo) UpdateUser(c context.Context, user *models.User) error {
 		args = append(args, *user.Name)
 		i++
 	}
-	if user.Avatar != nil {
-		query += fmt.Sprintf("avatar = $%d,", i)
-		args = append(args, *user.Avatar)
+	if user.Username != nil {
+		query += fmt.Sprintf("username = $%d,", i)
+		args = append(args, *user.Username)
 		i++
 	}
+	if user.Avatar != nil {
+		query += fmt.Sprintf("avatar = $%d, avatar_variants = $%d,", i, i+1)
+		args = append(args, *user.Avatar, user.AvatarVariants)
+		i += 2
+	}
 	if user.Bio != nil {
 		query += fmt.Sprintf("bio = $%d,", i)
 		args = append(args, *user.Bio)
@@ -84,3 +94,31 @@ func (ur *UserRepo) UpdateUser(c context.Context, user *models.User) error {
 	_, err := ur.db.Exec(c, query, args...)
 	return err
 }
+
+// SetPrivate switches the account between public and private. Going public
+// accepts every pending follow request.
+func (ur *UserRepo) SetPrivate(c context.Context, userId int, private bool) error {
+	tx, err := ur.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, `UPDATE users SET is_private = $1, updated_at = now() WHERE id = $2`, private, userId); err != nil {
+		return err
+	}
+
+	if !private {
+		query := `INSERT INTO follows (follower_id, following_id, created_at)
+		          SELECT requester_id, target_id, now() FROM follow_requests WHERE target_id = $1
+		          ON CONFLICT (follower_id, following_id) DO NOTHING`
+		if _, err := tx.Exec(c, query, userId); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(c, `DELETE FROM follow_requests WHERE target_id = $1`, userId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(c)
+}
@@ -0,0 +1,162 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// countInWindow increments a velocity counter and gives it the window as its
+// lifetime when it has none, in one step so a counter can never be left
+// without an expiry. It returns the new count.
+var countInWindow = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+type VelocityRepo struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewVelocityRepo(db *pgxpool.Pool, rdb *redis.Client) *VelocityRepo {
+	return &VelocityRepo{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// Track counts one occurrence of signal for the user, against subject when the
+// signal is about a particular target, and evaluates models.VelocityRules.
+// Rules that flag put the account in the moderation queue once per window.
+// It returns the strictest of the other actions that fired, reauth over
+// throttle, or "" when the request may go on.
+func (vr *VelocityRepo) Track(c context.Context, userId int, signal, subject string) (string, error) {
+	counts := map[time.Duration]int64{}
+	verdict := ""
+	for _, rule := range models.VelocityRules {
+		if rule.Signal != signal {
+			continue
+		}
+
+		count, counted := counts[rule.Window]
+		if !counted {
+			key := fmt.Sprintf("Mosting:velocity:%s:%d:%d", signal, int(rule.Window.Seconds()), userId)
+			if subject != "" {
+				key += ":" + subject
+			}
+			var err error
+			if count, err = countInWindow.Run(c, vr.rdb, []string{key}, rule.Window.Milliseconds()).Int64(); err != nil {
+				return "", err
+			}
+			counts[rule.Window] = count
+		}
+
+		if count <= rule.Limit {
+			continue
+		}
+		switch rule.Action {
+		case models.VelocityFlag:
+			if err := vr.flag(c, userId, rule, count); err != nil {
+				return "", err
+			}
+		case models.VelocityReauth:
+			verdict = models.VelocityReauth
+		case models.VelocityThrottle:
+			if verdict == "" {
+				verdict = models.VelocityThrottle
+			}
+		}
+	}
+	return verdict, nil
+}
+
+// EndSession blacklists the token so the user has to log in again.
+func (vr *VelocityRepo) EndSession(c context.Context, token string) error {
+	return utils.BlackListTokenRedish(c, *vr.rdb, token)
+}
+
+// flag records the signal and files a system report on the user, unless the
+// same signal already flagged them within the rule's window.
+func (vr *VelocityRepo) flag(c context.Context, userId int, rule models.VelocityRule, count int64) error {
+	key := "Mosting:velocity:flagged:" + rule.Signal + ":" + strconv.Itoa(userId)
+	first, err := vr.rdb.SetNX(c, key, "true", rule.Window).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	details := fmt.Sprintf("%s: %d times within %s", rule.Signal, count, rule.Window)
+
+	tx, err := vr.db.Begin(c)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(c)
+
+	query := `INSERT INTO abuse_flags (user_id, signal, count, window_seconds, details, created_at)
+	          VALUES ($1, $2, $3, $4, $5, now())`
+	if _, err := tx.Exec(c, query, userId, rule.Signal, count, int(rule.Window.Seconds()), details); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO reports (target_type, target_id, reason, details, status, created_at)
+	         VALUES ($1, $2, $3, $4, 'open', now())`
+	if _, err := tx.Exec(c, query, models.ReportTargetUser, userId, models.ReportReasonVelocity, details); err != nil {
+		return err
+	}
+	return tx.Commit(c)
+}
+
+// GetFlaggedAccounts returns flagged accounts, most recently flagged first,
+// with every signal that flagged them.
+func (vr *VelocityRepo) GetFlaggedAccounts(c context.Context, limit, offset int) ([]dtos.FlaggedAccountResponse, error) {
+	query := `WITH accounts AS (
+	            SELECT user_id, max(created_at) AS last_flagged_at
+	            FROM abuse_flags
+	            GROUP BY user_id
+	            ORDER BY last_flagged_at DESC, user_id
+	            LIMIT $1 OFFSET $2
+	          )
+	          SELECT a.user_id, u.username, f.id, f.signal, f.count, f.window_seconds, f.details, f.created_at
+	          FROM accounts a
+	          JOIN users u ON u.id = a.user_id
+	          JOIN abuse_flags f ON f.user_id = a.user_id
+	          ORDER BY a.last_flagged_at DESC, a.user_id, f.created_at DESC, f.id DESC`
+	rows, err := vr.db.Query(c, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []dtos.FlaggedAccountResponse{}
+	for rows.Next() {
+		var (
+			userId   int
+			username *string
+			f        dtos.AbuseFlagResponse
+		)
+		if err := rows.Scan(&userId, &username, &f.ID, &f.Signal, &f.Count, &f.WindowSeconds, &f.Details, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		if n := len(accounts); n == 0 || accounts[n-1].UserID != userId {
+			accounts = append(accounts, dtos.FlaggedAccountResponse{
+				UserID:   userId,
+				Username: username,
+				Flags:    []dtos.AbuseFlagResponse{},
+			})
+		}
+		last := &accounts[len(accounts)-1]
+		last.Flags = append(last.Flags, f)
+	}
+	return accounts, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repos
+
+import "fmt"
+
+// Visibility filters shared by the feed, comment, like and follow queries.
+// userCol is the column holding the author of the row and viewer is the
+// placeholder (e.g. "$1") holding the id of the user looking at it. An
+// anonymous viewer is passed as 0, which never matches a block or a mute.
+
+// notBlocked hides rows when either side blocked the other.
+func notBlocked(userCol, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.blocker_id = %[2]s AND b.blocked_id = %[1]s)
+		   OR (b.blocker_id = %[1]s AND b.blocked_id = %[2]s)
+	)`, userCol, viewer)
+}
+
+// notMuted hides rows written by users the viewer muted. Muting is one-way,
+// the muted user still sees everything of the viewer.
+func notMuted(userCol, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM mutes m WHERE m.muter_id = %[2]s AND m.muted_id = %[1]s
+	)`, userCol, viewer)
+}
+
+// visibleAccount hides rows of private accounts from everyone but the account
+// itself and its followers.
+func visibleAccount(userCol, viewer string) string {
+	return fmt.Sprintf(`(
+		%[1]s = %[2]s
+		OR NOT EXISTS (SELECT 1 FROM users pu WHERE pu.id = %[1]s AND pu.is_private)
+		OR EXISTS (SELECT 1 FROM follows pf WHERE pf.follower_id = %[2]s AND pf.following_id = %[1]s)
+	)`, userCol, viewer)
+}
+
+// postAudience hides posts whose audience does not include the viewer. Authors
+// always see their own posts. Unlisted posts are only included when listed is
+// false, i.e. when the post is opened directly or from the author's profile.
+func postAudience(postAlias, viewer string, listed bool) string {
+	unlisted := "true"
+	if listed {
+		unlisted = "false"
+	}
+	return fmt.Sprintf(`(
+		%[1]s.user_id = %[2]s
+		OR %[1]s.visibility = 'public'
+		OR (%[1]s.visibility = 'unlisted' AND %[3]s)
+		OR (%[1]s.visibility = 'followers'
+		    AND EXISTS (SELECT 1 FROM follows af WHERE af.follower_id = %[2]s AND af.following_id = %[1]s.user_id))
+		OR (%[1]s.visibility = 'close_friends'
+		    AND EXISTS (SELECT 1 FROM close_friends cf WHERE cf.user_id = %[1]s.user_id AND cf.friend_id = %[2]s))
+	)`, postAlias, viewer, unlisted)
+}
+
+// notHidden hides posts and comments taken down by a moderator from everyone
+// but their author.
+func notHidden(alias, viewer string) string {
+	return fmt.Sprintf(`(%[1]s.hidden_at IS NULL OR %[1]s.user_id = %[2]s)`, alias, viewer)
+}
+
+// published hides drafts and scheduled posts from everyone but their author.
+func published(postAlias, viewer string) string {
+	return fmt.Sprintf(`(%[1]s.status = 'published' OR %[1]s.user_id = %[2]s)`, postAlias, viewer)
+}
+
+// notSuspended hides rows written by users whose suspension is still running.
+func notSuspended(userCol string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM users su
+		WHERE su.id = %s AND su.suspended_at IS NOT NULL
+		  AND (su.suspended_until IS NULL OR su.suspended_until > now())
+	)`, userCol)
+}
+
+// noMutedWords hides rows whose text contains a word the viewer muted. Muted
+// words match whole words only, case-insensitively: muting "cat" hides "Cat
+// pictures" but not "category". The word is escaped so it is matched
+// literally.
+func noMutedWords(textCol, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM muted_words mw
+		WHERE mw.user_id = %[2]s
+		  AND %[1]s ~* ('(^|[^[:alnum:]_])' || regexp_replace(mw.word, '([^[:alnum:][:space:]_])', '\\\1', 'g') || '($|[^[:alnum:]_])')
+	)`, textCol, viewer)
+}
@@ -0,0 +1,36 @@
+package routers
+
+import (
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitAdminRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) {
+	reportRepo := repos.NewReportRepo(db, rdb)
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	suspensionRepo := repos.NewSuspensionRepo(db, rdb)
+	userRepo := repos.NewUserRepo(db, store)
+	moderationHandler := handlers.NewModerationHandler(reportRepo, notificationRepo, suspensionRepo)
+	suspensionHandler := handlers.NewSuspensionHandler(suspensionRepo, reportRepo, userRepo)
+	screeningHandler := handlers.NewScreeningHandler(repos.NewScreeningRepo(db, rdb))
+	abuseHandler := handlers.NewAbuseHandler(repos.NewVelocityRepo(db, rdb))
+
+	admin := router.Group("/admin", middlewares.RequiredToken(db, rdb), middlewares.RequiredModerator(db))
+	admin.GET("/reports", moderationHandler.GetReports)
+	admin.PATCH("/reports/:id", moderationHandler.ResolveReport)
+	admin.GET("/actions", moderationHandler.GetActions)
+	admin.POST("/actions", moderationHandler.TakeAction)
+	admin.POST("/users/:id/suspend", suspensionHandler.SuspendUser)
+	admin.DELETE("/users/:id/suspend", suspensionHandler.UnsuspendUser)
+	admin.GET("/appeals", suspensionHandler.GetAppeals)
+	admin.PATCH("/appeals/:id", suspensionHandler.ResolveAppeal)
+	admin.GET("/screening-rules", screeningHandler.GetRules)
+	admin.POST("/screening-rules", screeningHandler.CreateRule)
+	admin.DELETE("/screening-rules/:id", screeningHandler.DeleteRule)
+	admin.GET("/flags", abuseHandler.GetFlags)
+}
@@ -1,6 +1,8 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
@@ -12,9 +14,15 @@ import (
 func InitAuthRouter(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
 	auth := r.Group("/auth")
 	authRepo := repos.NewAuthRepo(db, rdb)
-	authHandler := handlers.NewAuthHandler(authRepo)
+	suspensionRepo := repos.NewSuspensionRepo(db, rdb)
+	authHandler := handlers.NewAuthHandler(authRepo, suspensionRepo)
+
+	registerLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "register", Limit: 5, Window: time.Hour})
+	loginLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "login", Limit: 10, Window: time.Minute})
+	appealLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "appeal", Limit: 3, Window: time.Hour})
 
-	auth.POST("/register", authHandler.Register)
-	auth.POST("/login", authHandler.Login)
-	auth.DELETE("/logout", middlewares.RequiredToken(rdb), authHandler.Logout)
+	auth.POST("/register", registerLimit, authHandler.Register)
+	auth.POST("/login", loginLimit, authHandler.Login)
+	auth.DELETE("/logout", middlewares.RequiredToken(db, rdb), authHandler.Logout)
+	auth.POST("/appeal", appealLimit, authHandler.Appeal)
 }
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitBookmarkRouter(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) {
+	bookmarkHandler := handlers.NewBookmarkHandler(repos.NewBookmarkRepo(db, rdb, store), repos.NewPostRepo(db, rdb, store))
+
+	posts := r.Group("/posts")
+	posts.POST("/:id/bookmark", middlewares.RequiredToken(db, rdb), bookmarkHandler.BookmarkPost)
+	posts.DELETE("/:id/bookmark", middlewares.RequiredToken(db, rdb), bookmarkHandler.UnbookmarkPost)
+
+	bookmarks := r.Group("/bookmarks", middlewares.RequiredToken(db, rdb))
+	bookmarks.GET("", bookmarkHandler.GetBookmarks)
+	bookmarks.GET("/collections", bookmarkHandler.GetCollections)
+	bookmarks.POST("/collections", bookmarkHandler.CreateCollection)
+	bookmarks.DELETE("/collections/:id", bookmarkHandler.DeleteCollection)
+}
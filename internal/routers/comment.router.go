@@ -1,22 +1,33 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitCommentRouter(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+func InitCommentRouter(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) {
 	commentRepo := repos.NewCommentRepo(db)
-	postRepo := repos.NewPostRepo(db, rdb)
-	commentHandler := handlers.NewCommentHandler(commentRepo, postRepo)
+	postRepo := repos.NewPostRepo(db, rdb, store)
+	mentionRepo := repos.NewMentionRepo(db)
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	eventRepo := repos.NewEventRepo(db, rdb)
+	reportRepo := repos.NewReportRepo(db, rdb)
+	screener := utils.Screeners{repos.NewScreeningRepo(db, rdb)}
+	commentHandler := handlers.NewCommentHandler(commentRepo, postRepo, mentionRepo, notificationRepo, eventRepo, reportRepo, screener)
+
+	createLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "comment_create", Limit: 30, Window: time.Minute})
 
 	post := r.Group("/posts")
-	post.POST("/:id/comments", middlewares.RequiredToken(rdb), commentHandler.CreateComment)
-	post.GET("/:id/comments", middlewares.RequiredToken(rdb), commentHandler.GetComments)
-	post.PUT("/comments/:id", middlewares.RequiredToken(rdb), commentHandler.UpdateComment)
-	post.DELETE("/comments/:id", middlewares.RequiredToken(rdb), commentHandler.DeleteComment)
+	post.POST("/:id/comments", middlewares.RequiredToken(db, rdb), createLimit, commentHandler.CreateComment)
+	post.GET("/:id/comments", middlewares.RequiredToken(db, rdb), commentHandler.GetComments)
+	post.PUT("/comments/:id", middlewares.RequiredToken(db, rdb), commentHandler.UpdateComment)
+	post.DELETE("/comments/:id", middlewares.RequiredToken(db, rdb), commentHandler.DeleteComment)
 }
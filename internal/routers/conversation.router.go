@@ -0,0 +1,33 @@
+package routers
+
+import (
+	"time"
+
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitConversationRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, policies utils.UploadPolicies) {
+	conversationRepo := repos.NewConversationRepo(db, store)
+	eventRepo := repos.NewEventRepo(db, rdb)
+	blockRepo := repos.NewBlockRepo(db, rdb)
+	followRepo := repos.NewFollowRepo(db, rdb)
+	mediaRepo := repos.NewMediaRepo(db, store)
+	conversationHandler := handlers.NewConversationHandler(conversationRepo, eventRepo, blockRepo, followRepo, store, mediaRepo, policies)
+
+	createLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "conversation_create", Limit: 20, Window: time.Hour})
+	messageLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "message_send", Limit: 60, Window: time.Minute})
+
+	conversationRouter := router.Group("/conversations", middlewares.RequiredToken(db, rdb))
+	conversationRouter.POST("", createLimit, conversationHandler.CreateConversation)
+	conversationRouter.GET("", conversationHandler.GetConversations)
+	conversationRouter.GET("/:id/messages", conversationHandler.GetMessages)
+	conversationRouter.POST("/:id/messages", messageLimit, conversationHandler.SendMessage)
+	conversationRouter.POST("/:id/read", conversationHandler.MarkRead)
+}
@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitEventRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+	eventRepo := repos.NewEventRepo(db, rdb)
+	authRepo := repos.NewAuthRepo(db, rdb)
+	suspensionRepo := repos.NewSuspensionRepo(db, rdb)
+	eventHandler := handlers.NewEventHandler(eventRepo, authRepo, suspensionRepo)
+
+	router.GET("/events", middlewares.RequiredToken(db, rdb), eventHandler.Stream)
+}
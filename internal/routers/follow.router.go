@@ -1,6 +1,8 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
@@ -10,14 +12,22 @@ import (
 )
 
 func InitFollowRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
-	followRepo := repos.NewFollowRepo(db)
-	followHandler := handlers.NewFollowHandler(followRepo)
+	followRepo := repos.NewFollowRepo(db, rdb)
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	blockRepo := repos.NewBlockRepo(db, rdb)
+	velocityRepo := repos.NewVelocityRepo(db, rdb)
+	followHandler := handlers.NewFollowHandler(followRepo, notificationRepo, blockRepo, velocityRepo)
+
+	followLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "follow", Limit: 60, Window: time.Hour})
 
 	follow := router.Group("/follow")
-	follow.POST("/:id", middlewares.RequiredToken(rdb), followHandler.FollowUser)
-	follow.DELETE("/:id", middlewares.RequiredToken(rdb), followHandler.UnfollowUser)
+	follow.POST("/:id", middlewares.RequiredToken(db, rdb), followLimit, followHandler.FollowUser)
+	follow.DELETE("/:id", middlewares.RequiredToken(db, rdb), followHandler.UnfollowUser)
+	follow.GET("/requests", middlewares.RequiredToken(db, rdb), followHandler.GetFollowRequests)
+	follow.POST("/requests/:id/accept", middlewares.RequiredToken(db, rdb), followHandler.AcceptFollowRequest)
+	follow.POST("/requests/:id/reject", middlewares.RequiredToken(db, rdb), followHandler.RejectFollowRequest)
 
 	users := router.Group("/users")
-	users.GET("/:id/followers", followHandler.GetFollowers)
-	users.GET("/:id/following", followHandler.GetFollowing)
+	users.GET("/:id/followers", middlewares.OptionalToken(db, rdb), followHandler.GetFollowers)
+	users.GET("/:id/following", middlewares.OptionalToken(db, rdb), followHandler.GetFollowing)
 }
@@ -1,22 +1,30 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitLikeRoutes(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+func InitLikeRoutes(r *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage) {
 	likeRepo := repos.NewLikeRepo(db)
-	postRepo := repos.NewPostRepo(db, rdb)
+	postRepo := repos.NewPostRepo(db, rdb, store)
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	eventRepo := repos.NewEventRepo(db, rdb)
+	velocityRepo := repos.NewVelocityRepo(db, rdb)
+
+	likeHandler := handlers.NewLikeHandler(likeRepo, postRepo, notificationRepo, eventRepo, velocityRepo)
 
-	likeHandler := handlers.NewLikeHandler(likeRepo, postRepo)
+	likeLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "like", Limit: 60, Window: time.Minute})
 
 	posts := r.Group("/posts")
-	posts.POST("/:id/like", middlewares.RequiredToken(rdb), likeHandler.LikePost)
-	posts.DELETE("/:id/like", middlewares.RequiredToken(rdb), likeHandler.UnlikePost)
-	posts.GET("/:id/likes", middlewares.RequiredToken(rdb), likeHandler.GetLikes)
+	posts.POST("/:id/like", middlewares.RequiredToken(db, rdb), likeLimit, likeHandler.LikePost)
+	posts.DELETE("/:id/like", middlewares.RequiredToken(db, rdb), likeHandler.UnlikePost)
+	posts.GET("/:id/likes", middlewares.RequiredToken(db, rdb), likeHandler.GetLikes)
 }
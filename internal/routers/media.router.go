@@ -0,0 +1,40 @@
+package routers
+
+import (
+	"time"
+
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// InitMediaRouter serves uploads ahead of posts and, when they are kept on the
+// local filesystem, the uploaded files. Other backends serve media
+// themselves.
+func InitMediaRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, policies utils.UploadPolicies) {
+	uploadRepo := repos.NewUploadRepo(db, rdb)
+	mediaRepo := repos.NewMediaRepo(db, store)
+	mediaHandler := handlers.NewMediaHandler(uploadRepo, mediaRepo, store, policies)
+
+	media := router.Group("/media")
+
+	uploadLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "media_upload", Limit: 30, Window: time.Minute})
+
+	media.POST("", middlewares.RequiredToken(db, rdb), uploadLimit, mediaHandler.UploadMedia)
+	media.POST("/uploads", middlewares.RequiredToken(db, rdb), uploadLimit, mediaHandler.StartUpload)
+	media.HEAD("/:id", middlewares.RequiredToken(db, rdb), mediaHandler.GetUploadOffset)
+	media.PATCH("/:id", middlewares.RequiredToken(db, rdb), mediaHandler.UploadChunk)
+
+	local, ok := store.(*storage.LocalStorage)
+	if !ok {
+		return
+	}
+
+	router.Static("/img", local.PublicDir)
+	router.GET("/media/*key", local.ServeSigned)
+}
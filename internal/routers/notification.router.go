@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitNotificationRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+
+	notifications := router.Group("/notifications", middlewares.RequiredToken(db, rdb))
+	notifications.GET("", notificationHandler.GetNotifications)
+	notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
+	notifications.PATCH("/read-all", notificationHandler.MarkAllRead)
+	notifications.PATCH("/:id/read", notificationHandler.MarkRead)
+	notifications.GET("/preferences", notificationHandler.GetPreferences)
+	notifications.PATCH("/preferences", notificationHandler.UpdatePreferences)
+}
@@ -1,23 +1,63 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitPostRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
-	postRepo := repos.NewPostRepo(db, rdb)
-	postHandler := handlers.NewPostHandler(postRepo)
+func newPostHandler(db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, postPolicy utils.PostPolicy) *handlers.PostHandler {
+	postRepo := repos.NewPostRepo(db, rdb, store)
+	mentionRepo := repos.NewMentionRepo(db)
+	notificationRepo := repos.NewNotificationRepo(db, rdb)
+	eventRepo := repos.NewEventRepo(db, rdb)
+	reportRepo := repos.NewReportRepo(db, rdb)
+	uploadRepo := repos.NewUploadRepo(db, rdb)
+	linkRepo := repos.NewLinkPreviewRepo(db, rdb, utils.NewUnfurler())
+	// further screeners, such as classifiers, are added to this list
+	screener := utils.Screeners{repos.NewScreeningRepo(db, rdb)}
+	return handlers.NewPostHandler(postRepo, mentionRepo, notificationRepo, eventRepo, reportRepo, screener, store, uploadRepo, linkRepo, postPolicy)
+}
+
+// StartPostScheduler publishes scheduled posts in the background. Every app
+// instance can run it, one at a time gets to publish.
+func StartPostScheduler(db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, postPolicy utils.PostPolicy) {
+	newPostHandler(db, rdb, store, postPolicy).StartScheduler()
+}
+
+func InitPostRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, postPolicy utils.PostPolicy) {
+	postHandler := newPostHandler(db, rdb, store, postPolicy)
 
 	posts := router.Group("/posts")
 
-	posts.POST("", middlewares.RequiredToken(rdb), postHandler.CreatePost)
-	posts.GET("", postHandler.GetAllPosts)
-	posts.GET("/:id", postHandler.GetPostByID)
-	posts.PATCH("/:id", middlewares.RequiredToken(rdb), postHandler.UpdatePost)
-	posts.DELETE("/:id", middlewares.RequiredToken(rdb), postHandler.DeletePost)
+	createLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "post_create", Limit: 10, Window: time.Minute})
+
+	posts.POST("", middlewares.RequiredToken(db, rdb), createLimit, postHandler.CreatePost)
+	posts.GET("", middlewares.OptionalToken(db, rdb), postHandler.GetAllPosts)
+	posts.GET("/trash", middlewares.RequiredToken(db, rdb), postHandler.GetTrash)
+	posts.GET("/drafts", middlewares.RequiredToken(db, rdb), postHandler.GetDrafts)
+	posts.GET("/:id", middlewares.OptionalToken(db, rdb), postHandler.GetPostByID)
+	posts.GET("/:id/revisions", middlewares.OptionalToken(db, rdb), postHandler.GetRevisions)
+	posts.PATCH("/:id", middlewares.RequiredToken(db, rdb), postHandler.UpdatePost)
+	posts.DELETE("/:id", middlewares.RequiredToken(db, rdb), postHandler.DeletePost)
+	posts.POST("/:id/restore", middlewares.RequiredToken(db, rdb), postHandler.RestorePost)
+	posts.PATCH("/:id/schedule", middlewares.RequiredToken(db, rdb), postHandler.SchedulePost)
+	posts.DELETE("/:id/schedule", middlewares.RequiredToken(db, rdb), postHandler.CancelSchedule)
+	posts.POST("/:id/publish", middlewares.RequiredToken(db, rdb), postHandler.PublishPost)
+	posts.POST("/:id/pin", middlewares.RequiredToken(db, rdb), postHandler.PinPost)
+	posts.DELETE("/:id/pin", middlewares.RequiredToken(db, rdb), postHandler.UnpinPost)
+
+	pollHandler := handlers.NewPollHandler(repos.NewPollRepo(db, rdb), repos.NewPostRepo(db, rdb, store))
+	voteLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "poll_vote", Limit: 60, Window: time.Minute})
+	posts.POST("/:id/poll/votes", middlewares.RequiredToken(db, rdb), voteLimit, pollHandler.Vote)
+
+	users := router.Group("/users")
+	users.GET("/:id/posts", middlewares.OptionalToken(db, rdb), postHandler.GetUserPosts)
 }
@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"time"
+
+	"github.com/Darari17/social-media/internal/handlers"
+	"github.com/Darari17/social-media/internal/middlewares"
+	"github.com/Darari17/social-media/internal/repos"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func InitReportRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+	reportRepo := repos.NewReportRepo(db, rdb)
+	reportHandler := handlers.NewReportHandler(reportRepo)
+
+	reportLimit := middlewares.RateLimit(rdb, middlewares.RateLimitPolicy{Name: "report", Limit: 20, Window: time.Hour})
+
+	reports := router.Group("/reports")
+	reports.POST("", middlewares.RequiredToken(db, rdb), reportLimit, reportHandler.CreateReport)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/Darari17/social-media/docs"
 	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
@@ -13,17 +15,23 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func InitRouter(db *pgxpool.Pool, rdb *redis.Client) *gin.Engine {
+func InitRouter(db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, policies utils.UploadPolicies, postPolicy utils.PostPolicy) *gin.Engine {
 	r := gin.Default()
 
 	InitAuthRouter(r, db, rdb)
-	InitUserRouter(r, db, rdb)
-	InitPostRouter(r, db, rdb)
+	InitUserRouter(r, db, rdb, store, policies)
+	InitPostRouter(r, db, rdb, store, postPolicy)
 	InitFollowRouter(r, db, rdb)
-	InitLikeRoutes(r, db, rdb)
-	InitCommentRouter(r, db, rdb)
-
-	r.Static("/img", "public")
+	InitLikeRoutes(r, db, rdb, store)
+	InitBookmarkRouter(r, db, rdb, store)
+	InitCommentRouter(r, db, rdb, store)
+	InitNotificationRouter(r, db, rdb)
+	InitEventRouter(r, db, rdb)
+	InitConversationRouter(r, db, rdb, store, policies)
+	InitReportRouter(r, db, rdb)
+	InitAdminRouter(r, db, rdb, store)
+
+	InitMediaRouter(r, db, rdb, store, policies)
 
 	docs.SwaggerInfo.BasePath = "/"
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
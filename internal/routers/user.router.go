@@ -4,17 +4,39 @@ import (
 	"github.com/Darari17/social-media/internal/handlers"
 	"github.com/Darari17/social-media/internal/middlewares"
 	"github.com/Darari17/social-media/internal/repos"
+	"github.com/Darari17/social-media/internal/storage"
+	"github.com/Darari17/social-media/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitUserRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client) {
+func InitUserRouter(router *gin.Engine, db *pgxpool.Pool, rdb *redis.Client, store storage.Storage, policies utils.UploadPolicies) {
 	user := router.Group("/users")
-	userRepo := repos.NewUserRepo(db)
-	userHandler := handlers.NewUserHandler(userRepo)
+	userRepo := repos.NewUserRepo(db, store)
+	mediaRepo := repos.NewMediaRepo(db, store)
+	userHandler := handlers.NewUserHandler(userRepo, mediaRepo, store, policies)
+	mentionHandler := handlers.NewMentionHandler(repos.NewMentionRepo(db))
+	blockRepo := repos.NewBlockRepo(db, rdb)
+	blockHandler := handlers.NewBlockHandler(blockRepo, userRepo)
+	closeFriendHandler := handlers.NewCloseFriendHandler(repos.NewCloseFriendRepo(db, rdb), userRepo, blockRepo)
+	mutedWordHandler := handlers.NewMutedWordHandler(repos.NewMutedWordRepo(db, rdb))
 
 	user.GET("", userHandler.GetAllUsers)
-	user.GET("/profile", middlewares.RequiredToken(rdb), userHandler.GetUserByID)
-	user.PATCH("/profile", middlewares.RequiredToken(rdb), userHandler.UpdateUser)
+	user.GET("/profile", middlewares.RequiredToken(db, rdb), userHandler.GetUserByID)
+	user.PATCH("/profile", middlewares.RequiredToken(db, rdb), userHandler.UpdateUser)
+	user.GET("/profile/storage", middlewares.RequiredToken(db, rdb), userHandler.GetStorageUsage)
+	user.GET("/profile/mentions", middlewares.RequiredToken(db, rdb), mentionHandler.GetMentions)
+	user.GET("/profile/close-friends", middlewares.RequiredToken(db, rdb), closeFriendHandler.GetCloseFriends)
+	user.POST("/profile/close-friends/:id", middlewares.RequiredToken(db, rdb), closeFriendHandler.AddCloseFriend)
+	user.DELETE("/profile/close-friends/:id", middlewares.RequiredToken(db, rdb), closeFriendHandler.RemoveCloseFriend)
+	user.GET("/profile/muted-words", middlewares.RequiredToken(db, rdb), mutedWordHandler.GetMutedWords)
+	user.POST("/profile/muted-words", middlewares.RequiredToken(db, rdb), mutedWordHandler.MuteWord)
+	user.DELETE("/profile/muted-words/:id", middlewares.RequiredToken(db, rdb), mutedWordHandler.UnmuteWord)
+	user.GET("/blocks", middlewares.RequiredToken(db, rdb), blockHandler.GetBlockedUsers)
+	user.GET("/mutes", middlewares.RequiredToken(db, rdb), blockHandler.GetMutedUsers)
+	user.POST("/:id/block", middlewares.RequiredToken(db, rdb), blockHandler.BlockUser)
+	user.DELETE("/:id/block", middlewares.RequiredToken(db, rdb), blockHandler.UnblockUser)
+	user.POST("/:id/mute", middlewares.RequiredToken(db, rdb), blockHandler.MuteUser)
+	user.DELETE("/:id/mute", middlewares.RequiredToken(db, rdb), blockHandler.UnmuteUser)
 }
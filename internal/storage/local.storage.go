@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Darari17/social-media/internal/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+// LocalStorage keeps media on the local filesystem. Public objects live in
+// PublicDir and are served as static files, private ones live in PrivateDir
+// and are served by ServeSigned.
+type LocalStorage struct {
+	PublicDir  string
+	PrivateDir string
+	// BaseURL prefixes public object URLs, SignedBaseURL signed ones.
+	BaseURL       string
+	SignedBaseURL string
+	SigningKey    []byte
+}
+
+func NewLocalStorage(publicDir, privateDir, baseURL, signedBaseURL string, signingKey []byte) *LocalStorage {
+	return &LocalStorage{
+		PublicDir:     publicDir,
+		PrivateDir:    privateDir,
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		SignedBaseURL: strings.TrimSuffix(signedBaseURL, "/"),
+		SigningKey:    signingKey,
+	}
+}
+
+func (ls *LocalStorage) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	if IsPrivate(key) {
+		return filepath.Join(ls.PrivateDir, filepath.FromSlash(strings.TrimPrefix(key, PrivatePrefix))), nil
+	}
+	return filepath.Join(ls.PublicDir, filepath.FromSlash(key)), nil
+}
+
+func (ls *LocalStorage) Put(c context.Context, key string, data []byte, contentType string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (ls *LocalStorage) Get(c context.Context, key string) ([]byte, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (ls *LocalStorage) Delete(c context.Context, key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (ls *LocalStorage) URL(key string) string {
+	return ls.BaseURL + "/" + key
+}
+
+func (ls *LocalStorage) SignedURL(c context.Context, key string, ttl time.Duration) (string, error) {
+	if !IsPrivate(key) {
+		return ls.URL(key), nil
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {ls.sign(key, expires)},
+	}
+	return ls.SignedBaseURL + "/" + key + "?" + query.Encode(), nil
+}
+
+func (ls *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, ls.SigningKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeSigned serves a private object behind a URL made by SignedURL. The
+// key is taken from the "key" wildcard parameter.
+func (ls *LocalStorage) ServeSigned(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires := c.Query("expires")
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !IsPrivate(key) || time.Now().Unix() > unix ||
+		!hmac.Equal([]byte(c.Query("signature")), []byte(ls.sign(key, expires))) {
+		c.JSON(http.StatusForbidden, dtos.Response{
+			Code:    http.StatusForbidden,
+			Success: false,
+			Message: "Link is invalid or has expired",
+		})
+		return
+	}
+
+	path, err := ls.path(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, dtos.Response{
+			Code:    http.StatusNotFound,
+			Success: false,
+			Message: "File not found",
+		})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(max(unix-time.Now().Unix(), 0), 10))
+	c.File(path)
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "posts_1.jpg", want: true},
+		{key: "private/messages_1.jpg", want: true},
+		{key: "a/b/c.jpg", want: true},
+		{key: "..jpg", want: true},
+		{key: "", want: false},
+		{key: "/etc/passwd", want: false},
+		{key: "../secret", want: false},
+		{key: "private/../../secret", want: false},
+		{key: "a/./b.jpg", want: false},
+		{key: "a//b.jpg", want: false},
+		{key: "a/", want: false},
+		{key: `..\secret`, want: false},
+		{key: `a\b.jpg`, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func newLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	dir := t.TempDir()
+	return NewLocalStorage(filepath.Join(dir, "public"), filepath.Join(dir, "private"), "/img", "/media", []byte("key"))
+}
+
+func TestLocalStorageObjects(t *testing.T) {
+	ls := newLocalStorage(t)
+	c := context.Background()
+
+	if err := ls.Put(c, "private/messages_1.jpg", []byte("image"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// private objects stay out of the public directory
+	if _, err := os.Stat(filepath.Join(ls.PrivateDir, "messages_1.jpg")); err != nil {
+		t.Errorf("private object not in PrivateDir: %v", err)
+	}
+
+	data, err := ls.Get(c, "private/messages_1.jpg")
+	if err != nil || string(data) != "image" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+
+	if err := ls.Delete(c, "private/messages_1.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := ls.Get(c, "private/messages_1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := ls.Delete(c, "private/messages_1.jpg"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+
+	for _, key := range []string{"../escape.jpg", "private/../../escape.jpg", "/escape.jpg"} {
+		if err := ls.Put(c, key, []byte("x"), "image/jpeg"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+	}
+}
+
+func TestLocalStorageServeSigned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ls := newLocalStorage(t)
+	c := context.Background()
+
+	if err := ls.Put(c, "private/messages_1.jpg", []byte("secret"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Put(c, "posts_1.jpg", []byte("public"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/media/*key", ls.ServeSigned)
+
+	valid, err := ls.SignedURL(c, "private/messages_1.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := ls.SignedURL(c, "private/messages_1.jpg", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered, _ := url.Parse(valid)
+	q := tampered.Query()
+	q.Set("signature", q.Get("signature")[1:]+"0")
+	tampered.RawQuery = q.Encode()
+
+	otherKey, _ := url.Parse(valid)
+	otherKey.Path = "/media/private/messages_2.jpg"
+
+	// a correctly signed link to a public object is refused all the same
+	expires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	public := "/media/posts_1.jpg?" + url.Values{
+		"expires":   {expires},
+		"signature": {ls.sign("posts_1.jpg", expires)},
+	}.Encode()
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid", target: valid, wantStatus: http.StatusOK, wantBody: "secret"},
+		{name: "bad signature", target: tampered.String(), wantStatus: http.StatusForbidden},
+		{name: "signature of another key", target: otherKey.String(), wantStatus: http.StatusForbidden},
+		{name: "expired", target: expired, wantStatus: http.StatusForbidden},
+		{name: "missing expiry", target: "/media/private/messages_1.jpg?signature=00", wantStatus: http.StatusForbidden},
+		{name: "public key", target: public, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
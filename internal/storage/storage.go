@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// PrivatePrefix starts the keys of objects that are not public, such as
+// direct message attachments. They are only reachable through signed URLs.
+const PrivatePrefix = "private/"
+
+// ErrNotFound is returned when reading an object that does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded media. Keys are slash separated paths like
+// "posts_1700000000.jpg" or "private/messages_1700000000.jpg".
+type Storage interface {
+	Put(c context.Context, key string, data []byte, contentType string) error
+	Get(c context.Context, key string) ([]byte, error)
+	Delete(c context.Context, key string) error
+	// URL is the address a public object is served at.
+	URL(key string) string
+	// SignedURL is an address for the object that stops working after ttl.
+	// Public objects get their plain URL.
+	SignedURL(c context.Context, key string, ttl time.Duration) (string, error)
+}
+
+func IsPrivate(key string) bool {
+	return strings.HasPrefix(key, PrivatePrefix)
+}
+
+// validKey keeps keys from escaping the storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
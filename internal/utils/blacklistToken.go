@@ -16,3 +16,15 @@ func BlackListTokenRedish(c context.Context, rdb redis.Client, token string) err
 	}
 	return nil
 }
+
+// IsBlacklisted tells whether the token was logged out.
+func IsBlacklisted(c context.Context, rdb *redis.Client, token string) (bool, error) {
+	isBlacklist, err := rdb.Get(c, "Mosting:blacklist:"+token).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return isBlacklist == "true", nil
+}
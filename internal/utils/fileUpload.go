@@ -1,34 +1,138 @@
 package utils
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
-	"path/filepath"
-	"regexp"
-	"time"
 
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
-func FileUpload(c *gin.Context, file *multipart.FileHeader, prefix string) (string, error) {
-	const maxSize = 2 * 1024 * 1024
-	if file.Size > maxSize {
-		return "", errors.New("file too large (max 2MB)")
+// MediaRegistry keeps track of stored files so identical uploads are stored
+// once and files nothing refers to anymore can be collected.
+type MediaRegistry interface {
+	// FindUpload returns the stored sizes of an upload, or nil when it is not
+	// stored. Finding an upload counts as using it.
+	FindUpload(c context.Context, upload string) (models.ImageVariants, error)
+	RegisterFiles(c context.Context, files []models.MediaFile) error
+}
+
+// FileUpload checks an uploaded image against the policy of its kind of media,
+// processes it (see ProcessImage) and saves it in every size. Files are named
+// after the hash of the upload, so uploading the same image again reuses the
+// stored files. The full size keeps the plain name, the others get the size
+// name appended. A policy prefix starting with storage.PrivatePrefix keeps
+// the files private.
+//
+// Stored files are never deleted here since other posts or users may share
+// them; files left without a reference are collected later.
+func FileUpload(c *gin.Context, store storage.Storage, registry MediaRegistry, file *multipart.FileHeader, policy UploadPolicy) (models.ImageVariants, error) {
+	if err := policy.CheckSize(file.Size); err != nil {
+		return nil, err
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, policy.MaxBytes+1))
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+
+	return StoreImage(c, store, registry, data, policy)
+}
+
+// StoreImage is FileUpload for an image already read into memory, such as one
+// put together from chunks.
+func StoreImage(c *gin.Context, store storage.Storage, registry MediaRegistry, data []byte, policy UploadPolicy) (models.ImageVariants, error) {
+	if err := policy.CheckSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	upload := policy.Prefix + "_" + hex.EncodeToString(sum[:])
+
+	variants, err := registry.FindUpload(c.Request.Context(), upload)
+	if err != nil {
+		log.Println("Failed to look up upload:", err)
+	}
+	if hasSizes(variants, policy.Sizes) {
+		return variants, nil
+	}
+
+	images, err := ProcessImage(data, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	variants = models.ImageVariants{}
+	files := make([]models.MediaFile, 0, len(images))
+	for _, img := range images {
+		filename := upload + img.Ext
+		if img.Size.Name != models.ImageFull {
+			filename = upload + "_" + img.Size.Name + img.Ext
+		}
+
+		if err = store.Put(c.Request.Context(), filename, img.Data, img.ContentType); err != nil {
+			log.Println("Failed to save upload:", err)
+			break
+		}
+		variants[img.Size.Name] = models.ImageVariant{
+			Filename: filename,
+			Width:    img.Width,
+			Height:   img.Height,
+		}
+		files = append(files, models.MediaFile{
+			Key:         filename,
+			Upload:      upload,
+			Variant:     img.Size.Name,
+			Width:       img.Width,
+			Height:      img.Height,
+			SizeBytes:   int64(len(img.Data)),
+			ContentType: img.ContentType,
+		})
 	}
 
-	ext := filepath.Ext(file.Filename)
-	re := regexp.MustCompile(`(?i)\.(png|jpg|jpeg|webp)$`)
-	if !re.MatchString(ext) {
-		return "", errors.New("invalid file type (only PNG, JPG, JPEG, WEBP allowed)")
+	// whatever got stored is registered, even after a failure, so it is
+	// collected when unused
+	if regErr := registry.RegisterFiles(c.Request.Context(), files); regErr != nil {
+		log.Println("Failed to register upload:", regErr)
+		if err == nil {
+			err = regErr
+		}
+	}
+	if err != nil {
+		return nil, errors.New("failed to save file")
 	}
 
-	filename := fmt.Sprintf("%s_%d%s", prefix, time.Now().UnixNano(), ext)
-	location := filepath.Join("public", filename)
+	return variants, nil
+}
 
-	if err := c.SaveUploadedFile(file, location); err != nil {
-		return "", errors.New("failed to save file")
+func hasSizes(variants models.ImageVariants, sizes []ImageSize) bool {
+	if len(variants) == 0 {
+		return false
 	}
+	for _, size := range sizes {
+		if _, ok := variants[size.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
 
-	return filename, nil
+// SetImageURLs fills in the URL of every variant.
+func SetImageURLs(store storage.Storage, variants models.ImageVariants) {
+	for name, v := range variants {
+		v.URL = store.URL(v.Filename)
+		variants[name] = v
+	}
 }
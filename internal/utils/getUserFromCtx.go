@@ -20,3 +20,19 @@ func GetUserFromCtx(c *gin.Context) (int, error) {
 
 	return userClaims.UserId, nil
 }
+
+// GetTokenFromCtx returns the token the request was authenticated with and its
+// claims.
+func GetTokenFromCtx(c *gin.Context) (string, *pkg.Claims, error) {
+	claims, ok := c.Get("claims")
+	if !ok {
+		return "", nil, errors.New("claims not found in context, token might be missing")
+	}
+
+	userClaims, ok := claims.(*pkg.Claims)
+	if !ok {
+		return "", nil, errors.New("invalid claims format")
+	}
+
+	return c.GetString("token"), userClaims, nil
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	stddraw "image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/Darari17/social-media/internal/models"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageSize is a size uploads are stored in: scaled down to fit within
+// MaxSize x MaxSize, or cropped to the centre square first when Square is set.
+// Images are never scaled up.
+type ImageSize struct {
+	Name    string
+	MaxSize int
+	Square  bool
+}
+
+var (
+	PostImageSizes = []ImageSize{
+		{Name: models.ImageThumbnail, MaxSize: 320},
+		{Name: models.ImageMedium, MaxSize: 1080},
+		{Name: models.ImageFull, MaxSize: 2048},
+	}
+	AvatarSizes = []ImageSize{
+		{Name: models.ImageThumbnail, MaxSize: 64, Square: true},
+		{Name: models.ImageMedium, MaxSize: 256, Square: true},
+		{Name: models.ImageFull, MaxSize: 512, Square: true},
+	}
+	MessageImageSizes = []ImageSize{
+		{Name: models.ImageFull, MaxSize: 2048},
+	}
+)
+
+// images bigger than this are refused before decoding so a small file cannot
+// claim huge dimensions and exhaust memory
+const maxImagePixels = 40_000_000
+
+// ImageTypes are the content types uploads can be processed from.
+var ImageTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// ProcessedImage is one encoded size of an upload.
+type ProcessedImage struct {
+	Size        ImageSize
+	Data        []byte
+	Ext         string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// ProcessImage checks by its content that data is an image of a type the
+// policy allows and within its dimensions, then decodes it, turns it upright
+// according to its EXIF orientation and re-encodes it in every size of the
+// policy. Re-encoding drops all metadata, EXIF and GPS included. Images with
+// transparency are stored as PNG, others as JPEG.
+func ProcessImage(data []byte, policy UploadPolicy) ([]ProcessedImage, error) {
+	if err := policy.CheckType(http.DetectContentType(data)); err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+	if err := policy.CheckDimensions(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w (max %d megapixels)", ErrFileTooLarge, maxImagePixels/1_000_000)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, decoded.Bounds().Dx(), decoded.Bounds().Dy()))
+	stddraw.Draw(src, src.Bounds(), decoded, decoded.Bounds().Min, stddraw.Src)
+	src = orient(src, jpegOrientation(data))
+
+	ext, contentType := ".jpg", "image/jpeg"
+	if !src.Opaque() {
+		ext, contentType = ".png", "image/png"
+	}
+
+	images := make([]ProcessedImage, 0, len(policy.Sizes))
+	for _, size := range policy.Sizes {
+		resized := resize(src, size)
+
+		var buf bytes.Buffer
+		if ext == ".png" {
+			err = png.Encode(&buf, resized)
+		} else {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, ProcessedImage{
+			Size:        size,
+			Data:        buf.Bytes(),
+			Ext:         ext,
+			ContentType: contentType,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+		})
+	}
+	return images, nil
+}
+
+func resize(src *image.RGBA, size ImageSize) *image.RGBA {
+	rect := src.Bounds()
+	if size.Square {
+		side := min(rect.Dx(), rect.Dy())
+		x := rect.Min.X + (rect.Dx()-side)/2
+		y := rect.Min.Y + (rect.Dy()-side)/2
+		rect = image.Rect(x, y, x+side, y+side)
+	}
+
+	width, height := rect.Dx(), rect.Dy()
+	if width > size.MaxSize || height > size.MaxSize {
+		if width >= height {
+			height = max(1, height*size.MaxSize/width)
+			width = size.MaxSize
+		} else {
+			width = max(1, width*size.MaxSize/height)
+			height = size.MaxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, rect, draw.Src, nil)
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) so the image is stored the way it
+// is meant to be shown.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90 counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, or returns 1 when
+// there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// start of scan, no metadata after this
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
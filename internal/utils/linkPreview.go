@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Darari17/social-media/internal/models"
+	"golang.org/x/net/html"
+)
+
+// Limits of fetching a page to preview it.
+const (
+	UnfurlTimeout   = 5 * time.Second
+	UnfurlMaxBytes  = 512 * 1024
+	unfurlRedirects = 3
+	maxLinkLength   = 2048
+)
+
+// ErrBlockedAddress is returned when a link leads to an address the server must
+// not reach from the outside, such as a private network or the server itself.
+var ErrBlockedAddress = errors.New("address not allowed")
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// FirstLink returns the first http or https link in text, without the
+// punctuation that usually follows a link in a sentence, or "" when there is
+// none.
+func FirstLink(text string) string {
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,!?;:)]}")
+		if len(link) > maxLinkLength {
+			continue
+		}
+		if u, err := url.Parse(link); err == nil && u.Host != "" {
+			return link
+		}
+	}
+	return ""
+}
+
+// blockedPrefixes are the ranges that are not public besides those the netip
+// predicates already tell apart.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// PublicAddr tells whether ip is a public unicast address.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicOnly refuses connections to anything but public addresses on the
+// http and https ports. It runs once the host name is resolved, for every
+// address tried and after every redirect, so a name cannot be made to point
+// at a private address between the check and the connection.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !PublicAddr(ip) || (port != "80" && port != "443") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	return nil
+}
+
+// Unfurler fetches the Open Graph and Twitter card metadata of web pages.
+// Client does the fetching and MaxBytes caps how much of a page is read.
+type Unfurler struct {
+	Client   *http.Client
+	MaxBytes int64
+}
+
+// NewUnfurler returns an unfurler that only reaches public addresses, gives
+// up after UnfurlTimeout and reads at most UnfurlMaxBytes of a page.
+func NewUnfurler() *Unfurler {
+	dialer := &net.Dialer{
+		Timeout: UnfurlTimeout,
+		Control: publicOnly,
+	}
+	transport := &http.Transport{
+		// a proxy would connect on our behalf, past the address check
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    UnfurlTimeout,
+		ResponseHeaderTimeout:  UnfurlTimeout,
+		MaxResponseHeaderBytes: 64 * 1024,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+	return &Unfurler{
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   UnfurlTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > unfurlRedirects {
+					return errors.New("too many redirects")
+				}
+				return checkLink(req.URL)
+			},
+		},
+		MaxBytes: UnfurlMaxBytes,
+	}
+}
+
+func checkLink(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, u.Redacted())
+	}
+	return nil
+}
+
+// Unfurl fetches the page at link and reads its preview. Pages that are not
+// HTML or describe nothing return an error.
+func (u *Unfurler) Unfurl(c context.Context, link string) (*models.LinkPreview, error) {
+	target, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLink(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MostingBot/1.0 (link preview)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("not a web page: %q", mediaType)
+	}
+
+	preview := readPreview(io.LimitReader(resp.Body, u.MaxBytes), resp.Request.URL)
+	if preview.Title == nil && preview.Description == nil {
+		return nil, errors.New("page has no preview")
+	}
+	preview.URL = link
+	return preview, nil
+}
+
+// readPreview reads the metadata in the head of a page. Open Graph tags win
+// over Twitter cards, which win over the plain title and description.
+func readPreview(r io.Reader, base *url.URL) *models.LinkPreview {
+	meta := map[string]string{}
+	var title string
+
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		name, hasAttr := z.TagName()
+		tag := string(name)
+
+		if tt == html.EndTagToken && tag == "head" {
+			break
+		}
+		if tt == html.StartTagToken && tag == "body" {
+			break
+		}
+		if tt == html.TextToken && inTitle && title == "" {
+			title = string(z.Text())
+		}
+		inTitle = tt == html.StartTagToken && tag == "title"
+
+		if (tt == html.StartTagToken || tt == html.SelfClosingTagToken) && tag == "meta" && hasAttr {
+			var key, content string
+			for {
+				k, v, more := z.TagAttr()
+				switch string(k) {
+				case "property", "name":
+					key = strings.ToLower(string(v))
+				case "content":
+					content = string(v)
+				}
+				if !more {
+					break
+				}
+			}
+			if _, ok := meta[key]; !ok && key != "" {
+				meta[key] = content
+			}
+		}
+	}
+
+	pick := func(keys ...string) *string {
+		for _, k := range keys {
+			if v := cleanText(meta[k]); v != "" {
+				return &v
+			}
+		}
+		return nil
+	}
+
+	preview := &models.LinkPreview{
+		Title:       pick("og:title", "twitter:title"),
+		Description: pick("og:description", "twitter:description", "description"),
+		SiteName:    pick("og:site_name"),
+	}
+	if preview.Title == nil {
+		if t := cleanText(title); t != "" {
+			preview.Title = &t
+		}
+	}
+	if image := pick("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != nil {
+		if ref, err := url.Parse(*image); err == nil {
+			abs := base.ResolveReference(ref)
+			if checkLink(abs) == nil && len(abs.String()) <= maxLinkLength {
+				s := abs.String()
+				preview.ImageURL = &s
+			}
+		}
+	}
+	truncate(preview.Title, 300)
+	truncate(preview.Description, 1000)
+	truncate(preview.SiteName, 100)
+	return preview
+}
+
+// cleanText drops invalid UTF-8 and folds whitespace; the tokenizer already
+// unescaped entities.
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s *string, n int) {
+	if s == nil || utf8.RuneCountInString(*s) <= n {
+		return
+	}
+	*s = string([]rune(*s)[:n])
+}
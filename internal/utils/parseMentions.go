@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9_]{3,30})`)
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+type MentionToken struct {
+	Username string
+	Offset   int
+	Length   int
+}
+
+// ParseMentions finds every @username in text. Offset and Length are counted
+// in characters (runes), not bytes, so clients can highlight them directly.
+func ParseMentions(text string) []MentionToken {
+	var tokens []MentionToken
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+
+		// skip emails (user@mail.com) and handles longer than the allowed length
+		if start > 0 && isHandleByte(text[start-1]) {
+			continue
+		}
+		if end < len(text) && isHandleByte(text[end]) {
+			continue
+		}
+
+		tokens = append(tokens, MentionToken{
+			Username: text[loc[2]:loc[3]],
+			Offset:   utf8.RuneCountInString(text[:start]),
+			Length:   utf8.RuneCountInString(text[start:end]),
+		})
+	}
+	return tokens
+}
+
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+func isHandleByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "time"
+
+// PostPolicy is how long posts can be edited after posting and restored after
+// being deleted, how often scheduled posts are published and how many posts
+// can be pinned to a profile.
+type PostPolicy struct {
+	// 0 keeps posts editable
+	EditWindow time.Duration
+	// deleted posts are purged for good after this
+	TrashRetention time.Duration
+	// how often posts whose publish_at has come are published
+	ScheduleInterval time.Duration
+	MaxPinned        int
+}
+
+// DefaultPostPolicy applies unless configured otherwise.
+var DefaultPostPolicy = PostPolicy{
+	TrashRetention:   30 * 24 * time.Hour,
+	ScheduleInterval: 30 * time.Second,
+	MaxPinned:        3,
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/Darari17/social-media/internal/models"
+)
+
+// Screener checks text written by a user before it is stored. New checks,
+// such as classifiers, only need to implement it and be added to the
+// Screeners wired in the routers.
+type Screener interface {
+	Screen(c context.Context, text string) (ScreenResult, error)
+}
+
+// ScreenResult is the outcome a screener wants applied (one of
+// models.ScreenOutcomes) and what made it decide so.
+type ScreenResult struct {
+	Outcome string
+	Matches []string
+}
+
+// Screeners runs every screener in order and keeps the strictest outcome. It
+// stops at the first rejection.
+type Screeners []Screener
+
+func (ss Screeners) Screen(c context.Context, text string) (ScreenResult, error) {
+	result := ScreenResult{Outcome: models.ScreenAllow}
+	for _, s := range ss {
+		r, err := s.Screen(c, text)
+		if err != nil {
+			return ScreenResult{}, err
+		}
+
+		result.Outcome = StricterOutcome(result.Outcome, r.Outcome)
+		result.Matches = append(result.Matches, r.Matches...)
+
+		if result.Outcome == models.ScreenReject {
+			break
+		}
+	}
+	return result, nil
+}
+
+// StricterOutcome returns the stricter of two screening outcomes.
+func StricterOutcome(a, b string) string {
+	if screenSeverity(b) > screenSeverity(a) {
+		return b
+	}
+	return a
+}
+
+func screenSeverity(outcome string) int {
+	for i, o := range models.ScreenOutcomes {
+		if o == outcome {
+			return i
+		}
+	}
+	return 0
+}
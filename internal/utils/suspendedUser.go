@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SuspensionCheckTTL is how long the suspension state read from the database
+// is cached under SuspendedKey.
+const SuspensionCheckTTL = time.Minute
+
+// SuspendedKey is the Redis key caching whether a user is suspended, "true" or
+// "false". RequiredToken checks it on every request so a suspension ends
+// existing sessions right away.
+func SuspendedKey(userId int) string {
+	return "Mosting:suspended:" + strconv.Itoa(userId)
+}
+
+// MarkSuspendedRedis flags the user as suspended for ttl, or until removed
+// when ttl is 0.
+func MarkSuspendedRedis(c context.Context, rdb *redis.Client, userId int, ttl time.Duration) error {
+	if err := rdb.Set(c, SuspendedKey(userId), "true", ttl).Err(); err != nil {
+		log.Println("Redis Error when marking user suspended:", err)
+		return err
+	}
+	return nil
+}
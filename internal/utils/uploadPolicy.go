@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Darari17/social-media/internal/models"
+	"github.com/Darari17/social-media/internal/storage"
+)
+
+// Errors of uploads breaking their policy wrap these, so they can be told
+// apart from broken images.
+var (
+	ErrFileTooLarge = errors.New("file too large")
+	ErrFileType     = errors.New("unsupported file type")
+	ErrQuotaFull    = errors.New("storage quota exceeded")
+)
+
+// UploadPolicy is what an upload of one kind of media may be: its size in
+// bytes, its content types and the largest width and height of the image.
+// Files are stored under Prefix in every one of Sizes.
+type UploadPolicy struct {
+	Kind      string
+	Prefix    string
+	Sizes     []ImageSize
+	MaxBytes  int64
+	Types     []string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// UploadPolicies holds the policy of every kind of media and the storage
+// quota of users who have none of their own, in bytes.
+type UploadPolicies struct {
+	Kinds        map[string]UploadPolicy
+	DefaultQuota int64
+}
+
+// DefaultUploadPolicies apply unless configured otherwise.
+var DefaultUploadPolicies = UploadPolicies{
+	Kinds: map[string]UploadPolicy{
+		models.MediaAvatar: {
+			Kind:      models.MediaAvatar,
+			Prefix:    "avatar",
+			Sizes:     AvatarSizes,
+			MaxBytes:  2 * 1024 * 1024,
+			Types:     ImageTypes,
+			MaxWidth:  4096,
+			MaxHeight: 4096,
+		},
+		models.MediaPost: {
+			Kind:      models.MediaPost,
+			Prefix:    "posts",
+			Sizes:     PostImageSizes,
+			MaxBytes:  20 * 1024 * 1024,
+			Types:     ImageTypes,
+			MaxWidth:  8192,
+			MaxHeight: 8192,
+		},
+		models.MediaMessage: {
+			Kind:      models.MediaMessage,
+			Prefix:    storage.PrivatePrefix + "messages",
+			Sizes:     MessageImageSizes,
+			MaxBytes:  5 * 1024 * 1024,
+			Types:     ImageTypes,
+			MaxWidth:  8192,
+			MaxHeight: 8192,
+		},
+	},
+	DefaultQuota: 500 * 1024 * 1024,
+}
+
+// Policy returns the policy of a kind of media.
+func (p UploadPolicies) Policy(kind string) UploadPolicy {
+	return p.Kinds[kind]
+}
+
+// CheckSize refuses files bigger than the policy allows.
+func (p UploadPolicy) CheckSize(size int64) error {
+	if size > p.MaxBytes {
+		return fmt.Errorf("%w (max %s)", ErrFileTooLarge, FormatBytes(p.MaxBytes))
+	}
+	return nil
+}
+
+// CheckType refuses content types the policy does not list.
+func (p UploadPolicy) CheckType(contentType string) error {
+	if !slices.Contains(p.Types, contentType) {
+		names := make([]string, len(p.Types))
+		for i, t := range p.Types {
+			names[i] = strings.ToUpper(strings.TrimPrefix(t, "image/"))
+		}
+		return fmt.Errorf("%w (only %s allowed)", ErrFileType, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// CheckDimensions refuses images wider or taller than the policy allows.
+func (p UploadPolicy) CheckDimensions(width, height int) error {
+	if width > p.MaxWidth || height > p.MaxHeight {
+		return fmt.Errorf("%w (max %dx%d pixels)", ErrFileTooLarge, p.MaxWidth, p.MaxHeight)
+	}
+	return nil
+}
+
+// FormatBytes writes a size the way people read it, such as 2MB or 512KB.
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024*1024 && n%(1024*1024*1024) == 0:
+		return fmt.Sprintf("%dGB", n/(1024*1024*1024))
+	case n >= 1024*1024 && n%(1024*1024) == 0:
+		return fmt.Sprintf("%dMB", n/(1024*1024))
+	case n >= 1024 && n%1024 == 0:
+		return fmt.Sprintf("%dKB", n/1024)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}